@@ -10,8 +10,11 @@ import (
 	"time"
 
 	"task-scheduler/internal/api"
+	"task-scheduler/internal/blob"
 	"task-scheduler/internal/config"
+	"task-scheduler/internal/keyring"
 	mysqlrepo "task-scheduler/internal/repo/mysql"
+	"task-scheduler/internal/service"
 )
 
 func main() {
@@ -26,8 +29,60 @@ func main() {
 	}
 	defer db.Close()
 
-	jobRepo := mysqlrepo.NewJobRepo(db)
-	server := api.NewServer(jobRepo)
+	var kr *keyring.Keyring
+	if cfg.PayloadKeyringFile != "" {
+		if kr, err = keyring.Load(cfg.PayloadKeyringFile); err != nil {
+			log.Fatalf("load payload keyring: %v", err)
+		}
+	}
+
+	blobs, err := blob.Open(cfg.Blob)
+	if err != nil {
+		log.Fatalf("open blob store: %v", err)
+	}
+	payloads := mysqlrepo.PayloadOptions{
+		CompressMin:   cfg.CompressMinBytes,
+		Keyring:       kr,
+		Blobs:         blobs,
+		BlobThreshold: cfg.BlobThresholdBytes,
+	}
+
+	static, err := service.LoadSchemaFile(cfg.SchemasFile)
+	if err != nil {
+		log.Fatalf("load job schemas: %v", err)
+	}
+	schemas, err := service.NewSchemaRegistry(mysqlrepo.NewPayloadSchemaRepo(db), static, cfg.SchemaCacheTTL)
+	if err != nil {
+		log.Fatalf("load job schemas: %v", err)
+	}
+
+	jobs := service.NewJobService(mysqlrepo.NewJobRepo(db, payloads), service.Policy{
+		MaxPayloadBytes:  cfg.MaxPayloadBytes,
+		AllowedTypes:     cfg.AllowedJobTypes,
+		MaxAttemptsLimit: cfg.MaxAttemptsLimit,
+		IdempotencyTTL:   cfg.IdempotencyTTL,
+	})
+	jobs.Schemas = schemas
+
+	server := api.NewServer(api.Deps{
+		Jobs:       jobs,
+		Events:     mysqlrepo.NewEventRepo(db),
+		Webhooks:   mysqlrepo.NewWebhookRepo(db),
+		APIKeys:    mysqlrepo.NewAPIKeyRepo(db),
+		Tenants:    mysqlrepo.NewTenantRepo(db),
+		RateLimits: mysqlrepo.NewRateLimitRepo(db),
+
+		ConcurrencyLimits: mysqlrepo.NewConcurrencyLimitRepo(db),
+		Workflows:         mysqlrepo.NewWorkflowRepo(db, payloads),
+		Batches:           mysqlrepo.NewBatchRepo(db, payloads),
+		Queues:            mysqlrepo.NewQueueRepo(db),
+		Schemas:           schemas,
+		Retention:         mysqlrepo.NewRetentionRepo(db, payloads),
+		Keyring:           kr,
+		Blobs:             blobs,
+
+		BootstrapKey: cfg.AdminAPIKey,
+	})
 
 	httpServer := &http.Server{
 		Addr:              ":" + cfg.Port,
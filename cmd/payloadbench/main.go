@@ -0,0 +1,172 @@
+// Command payloadbench measures what payload compression does to row size
+// and claim throughput. For each mode (uncompressed, then gzip) it inserts
+// -n jobs with a generated JSON payload of about -size bytes, reports their
+// average stored payload size, then claims them all in batches of -claim
+// and reports jobs claimed per second, decoding included.
+//
+//	DB_DSN=... payloadbench -n 2000 -size 16384
+//
+// Run it against a scratch database: claims take every due job, not only
+// the benchmark's. Its jobs are deleted afterwards.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"task-scheduler/internal/codec"
+	"task-scheduler/internal/config"
+	"task-scheduler/internal/domain"
+	mysqlrepo "task-scheduler/internal/repo/mysql"
+)
+
+func main() {
+	n := flag.Int("n", 2000, "jobs per mode")
+	size := flag.Int("size", 16<<10, "approximate payload size in bytes")
+	minBytes := flag.Int("min", 1024, "compression threshold for the gzip mode")
+	claim := flag.Int("claim", 50, "jobs per claim")
+	flag.Parse()
+
+	cfg := config.Load()
+	if cfg.DBDSN == "" {
+		log.Fatal("DB_DSN is required")
+	}
+	db, err := mysqlrepo.Open(cfg.DBDSN)
+	if err != nil {
+		log.Fatalf("db open failed: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	payload := samplePayload(*size)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODE\tPAYLOAD\tSTORED AVG\tSTORED TOTAL\tINSERT/S\tCLAIM/S")
+	for _, mode := range []struct {
+		name     string
+		minBytes int
+	}{{codec.Identity, 0}, {codec.Gzip, *minBytes}} {
+		repo := mysqlrepo.NewJobRepo(db, mysqlrepo.PayloadOptions{CompressMin: mode.minBytes})
+		runID := strconv.FormatInt(time.Now().UnixNano(), 36)
+		tenant := "bench-" + mode.name + "-" + runID
+
+		r, err := run(ctx, db, repo, runID, tenant, payload, *n, *claim)
+		cleanup(ctx, db, tenant)
+		if err != nil {
+			log.Fatalf("%s: %v", mode.name, err)
+		}
+		fmt.Fprintf(tw, "%s\t%d B\t%.0f B\t%d KiB\t%.0f\t%.0f\n",
+			mode.name, len(payload), r.avgStored, r.totalStored>>10, r.insertRate, r.claimRate)
+	}
+	tw.Flush()
+}
+
+type result struct {
+	avgStored   float64
+	totalStored int64
+	insertRate  float64
+	claimRate   float64
+}
+
+func run(ctx context.Context, db *sql.DB, repo *mysqlrepo.JobRepo, runID, tenant string, payload json.RawMessage, n, claim int) (result, error) {
+	var r result
+
+	specs := make([]domain.JobSpec, n)
+	for i := range specs {
+		specs[i] = domain.JobSpec{
+			ID:       fmt.Sprintf("bench-%s-%06d", runID, i),
+			TenantID: tenant,
+			Type:     "bench",
+			Payload:  payload,
+		}
+	}
+	start := time.Now()
+	for _, res := range repo.CreateJobs(ctx, specs) {
+		if res.Err != nil {
+			return r, fmt.Errorf("create jobs: %w", res.Err)
+		}
+	}
+	r.insertRate = float64(n) / time.Since(start).Seconds()
+
+	if err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(AVG(JSON_STORAGE_SIZE(payload)), 0), COALESCE(SUM(JSON_STORAGE_SIZE(payload)), 0)
+		FROM jobs
+		WHERE tenant_id = ?
+	`, tenant).Scan(&r.avgStored, &r.totalStored); err != nil {
+		return r, fmt.Errorf("measure rows: %w", err)
+	}
+
+	claimed := 0
+	start = time.Now()
+	for claimed < n {
+		jobs, err := repo.ClaimJobs(ctx, "payloadbench", claim, time.Hour, time.Now())
+		if err != nil {
+			return r, fmt.Errorf("claim: %w", err)
+		}
+		if len(jobs) == 0 {
+			break
+		}
+		for _, j := range jobs {
+			if j.TenantID == tenant {
+				claimed++
+			}
+		}
+	}
+	r.claimRate = float64(claimed) / time.Since(start).Seconds()
+	return r, nil
+}
+
+func cleanup(ctx context.Context, db *sql.DB, tenant string) {
+	for _, q := range []string{
+		`DELETE FROM job_events WHERE tenant_id = ?`,
+		`DELETE FROM jobs WHERE tenant_id = ?`,
+	} {
+		if _, err := db.ExecContext(ctx, q, tenant); err != nil {
+			log.Printf("cleanup %s: %v", tenant, err)
+		}
+	}
+}
+
+// samplePayload builds an order-like document of about size bytes: records
+// with repeated keys and varied values, as real payloads tend to be.
+func samplePayload(size int) json.RawMessage {
+	rng := rand.New(rand.NewSource(1))
+	words := strings.Fields("alpha bravo charlie delta echo foxtrot golf hotel india juliet kilo lima mike november oscar papa")
+	type item struct {
+		SKU         string  `json:"sku"`
+		Quantity    int     `json:"quantity"`
+		UnitPrice   float64 `json:"unit_price"`
+		Description string  `json:"description"`
+	}
+	doc := struct {
+		OrderID  string `json:"order_id"`
+		Customer string `json:"customer_email"`
+		Items    []item `json:"items"`
+	}{OrderID: fmt.Sprintf("ord-%08d", rng.Intn(1e8)), Customer: "customer@example.com"}
+
+	for {
+		b, _ := json.Marshal(doc)
+		if len(b) >= size {
+			return b
+		}
+		desc := make([]string, 6)
+		for i := range desc {
+			desc[i] = words[rng.Intn(len(words))]
+		}
+		doc.Items = append(doc.Items, item{
+			SKU:         fmt.Sprintf("SKU-%06d", rng.Intn(1e6)),
+			Quantity:    1 + rng.Intn(9),
+			UnitPrice:   float64(rng.Intn(100000)) / 100,
+			Description: strings.Join(desc, " "),
+		})
+	}
+}
@@ -0,0 +1,269 @@
+// Command schedctl manages the scheduler through its HTTP API.
+//
+//	schedctl [-server URL] [-api-key KEY] [-o table|json] <command> [flags] [args]
+//
+// The server and key default to $SCHEDCTL_SERVER and $SCHEDCTL_API_KEY.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"task-scheduler/pkg/client"
+)
+
+const usage = `usage: schedctl [-server URL] [-api-key KEY] [-o table|json] <command> [flags] [args]
+
+commands:
+  submit -type TYPE [-payload FILE|-] [-queue Q] [-priority N] [-delay D]
+         [-max-attempts N] [-idempotency-key K] [-wait]
+  get ID
+  list [-status S] [-type T] [-queue Q] [-limit N] [-cursor C]
+  tail [-job ID] [-type T] [-queue Q] [-after EVENT_ID]
+  cancel ID
+  retry ID
+  requeue-dead [-type T] [-queue Q] [-limit N]
+  pause QUEUE
+  resume QUEUE
+  stats
+`
+
+func main() {
+	global := flag.NewFlagSet("schedctl", flag.ExitOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	server := global.String("server", envOr("SCHEDCTL_SERVER", "http://localhost:8086"), "API base URL")
+	apiKey := global.String("api-key", os.Getenv("SCHEDCTL_API_KEY"), "API key")
+	output := global.String("o", "table", "output format: table or json")
+	_ = global.Parse(os.Args[1:])
+
+	args := global.Args()
+	if len(args) == 0 {
+		global.Usage()
+		os.Exit(2)
+	}
+	if *output != "table" && *output != "json" {
+		fatalf("-o must be table or json")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cli := &cli{
+		api: client.New(*server, client.WithAPIKey(*apiKey)),
+		out: newPrinter(os.Stdout, *output == "json"),
+	}
+	if err := cli.run(ctx, args[0], args[1:]); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		fatalf("%s: %v", args[0], err)
+	}
+}
+
+type cli struct {
+	api *client.Client
+	out *printer
+}
+
+func (c *cli) run(ctx context.Context, cmd string, args []string) error {
+	switch cmd {
+	case "submit":
+		return c.submit(ctx, args)
+	case "get":
+		id, err := oneArg(cmd, args, "ID")
+		if err != nil {
+			return err
+		}
+		job, err := c.api.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		return c.out.job(job)
+	case "list":
+		return c.list(ctx, args)
+	case "tail":
+		return c.tail(ctx, args)
+	case "cancel", "retry":
+		id, err := oneArg(cmd, args, "ID")
+		if err != nil {
+			return err
+		}
+		var job *client.Job
+		if cmd == "cancel" {
+			job, err = c.api.Cancel(ctx, id)
+		} else {
+			job, err = c.api.Retry(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+		return c.out.job(job)
+	case "requeue-dead":
+		return c.requeueDead(ctx, args)
+	case "pause", "resume":
+		queue, err := oneArg(cmd, args, "QUEUE")
+		if err != nil {
+			return err
+		}
+		if cmd == "pause" {
+			err = c.api.PauseQueue(ctx, queue)
+		} else {
+			err = c.api.ResumeQueue(ctx, queue)
+		}
+		if err != nil {
+			return err
+		}
+		return c.out.queueState(queue, cmd == "pause")
+	case "stats":
+		queues, err := c.api.Queues(ctx)
+		if err != nil {
+			return err
+		}
+		return c.out.stats(queues)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+		return nil
+	}
+}
+
+func (c *cli) submit(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("submit", flag.ExitOnError)
+	jobType := fs.String("type", "", "job type (required)")
+	payloadFrom := fs.String("payload", "-", `payload JSON file, or "-" for stdin`)
+	queue := fs.String("queue", "", "queue")
+	priority := fs.Int("priority", 0, "priority, -100 to 100")
+	delay := fs.Duration("delay", 0, "delay before the first run, e.g. 30s")
+	maxAttempts := fs.Int("max-attempts", 0, "max attempts (server default if 0)")
+	idemKey := fs.String("idempotency-key", "", "idempotency key")
+	wait := fs.Bool("wait", false, "wait until the job finishes")
+	_ = fs.Parse(args)
+
+	if *jobType == "" {
+		return errors.New("-type is required")
+	}
+	payload, err := readPayload(*payloadFrom)
+	if err != nil {
+		return err
+	}
+
+	job, err := c.api.Create(ctx, client.CreateJobRequest{
+		Type:           *jobType,
+		Queue:          *queue,
+		Payload:        payload,
+		MaxAttempts:    *maxAttempts,
+		Priority:       *priority,
+		Delay:          *delay,
+		IdempotencyKey: *idemKey,
+	})
+	if err != nil {
+		return err
+	}
+	if *wait {
+		if job, err = c.api.Wait(ctx, job.ID, time.Second); err != nil {
+			return err
+		}
+	}
+	return c.out.job(job)
+}
+
+func (c *cli) list(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	status := fs.String("status", "", "status, e.g. FAILED")
+	jobType := fs.String("type", "", "job type")
+	queue := fs.String("queue", "", "queue")
+	limit := fs.Int("limit", 50, "page size")
+	cursor := fs.String("cursor", "", "cursor from a previous page")
+	_ = fs.Parse(args)
+
+	page, err := c.api.List(ctx, client.ListOptions{
+		Status: client.JobStatus(*status),
+		Type:   *jobType,
+		Queue:  *queue,
+		Limit:  *limit,
+		Cursor: *cursor,
+	})
+	if err != nil {
+		return err
+	}
+	return c.out.jobs(page)
+}
+
+func (c *cli) tail(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("tail", flag.ExitOnError)
+	jobID := fs.String("job", "", "only events of this job")
+	jobType := fs.String("type", "", "job type")
+	queue := fs.String("queue", "", "queue")
+	after := fs.Int64("after", 0, "start after this event ID (default: new events only)")
+	_ = fs.Parse(args)
+
+	return c.api.Events(ctx, client.EventOptions{
+		JobID:   *jobID,
+		Type:    *jobType,
+		Queue:   *queue,
+		AfterID: *after,
+	}, c.out.event)
+}
+
+func (c *cli) requeueDead(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("requeue-dead", flag.ExitOnError)
+	jobType := fs.String("type", "", "job type")
+	queue := fs.String("queue", "", "queue")
+	limit := fs.Int("limit", 100, "max jobs to requeue (up to 1000)")
+	_ = fs.Parse(args)
+
+	res, err := c.api.RetryJobs(ctx, client.RetryJobsRequest{
+		Status: "FAILED",
+		Type:   *jobType,
+		Queue:  *queue,
+		Limit:  *limit,
+	})
+	if err != nil {
+		return err
+	}
+	return c.out.retried(res)
+}
+
+func readPayload(from string) (json.RawMessage, error) {
+	var data []byte
+	var err error
+	if from == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(from)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read payload: %w", err)
+	}
+	if !json.Valid(data) {
+		return nil, errors.New("payload is not valid JSON")
+	}
+	return json.RawMessage(data), nil
+}
+
+func oneArg(cmd string, args []string, name string) (string, error) {
+	if len(args) != 1 || args[0] == "" {
+		return "", fmt.Errorf("usage: schedctl %s %s", cmd, name)
+	}
+	return args[0], nil
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "schedctl: "+format+"\n", args...)
+	os.Exit(1)
+}
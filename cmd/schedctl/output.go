@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"task-scheduler/pkg/client"
+)
+
+// printer renders results as aligned tables, or as JSON with -o json.
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+func newPrinter(w io.Writer, asJSON bool) *printer {
+	return &printer{w: w, json: asJSON}
+}
+
+func (p *printer) encode(v any) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (p *printer) table(header string, rows [][]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, header)
+	for _, r := range rows {
+		fmt.Fprintln(tw, strings.Join(r, "\t"))
+	}
+	return tw.Flush()
+}
+
+func (p *printer) job(j *client.Job) error {
+	if p.json {
+		return p.encode(j)
+	}
+	rows := [][]string{
+		{"ID", j.ID},
+		{"TYPE", j.Type},
+		{"QUEUE", j.Queue},
+		{"STATUS", string(j.Status)},
+		{"PRIORITY", fmt.Sprint(j.Priority)},
+		{"ATTEMPTS", fmt.Sprintf("%d/%d", j.Attempts, j.MaxAttempts)},
+		{"NEXT RUN", fmtTime(j.NextRunAt)},
+		{"CREATED", j.CreatedAt.Format(time.RFC3339)},
+		{"COMPLETED", fmtTime(j.CompletedAt)},
+	}
+	if j.ErrorMessage != nil {
+		rows = append(rows, []string{"ERROR", *j.ErrorMessage})
+	}
+	rows = append(rows, []string{"PAYLOAD", string(j.Payload)})
+	return p.table("FIELD\tVALUE", rows)
+}
+
+func (p *printer) jobs(page *client.JobPage) error {
+	if p.json {
+		return p.encode(page)
+	}
+	rows := make([][]string, 0, len(page.Jobs))
+	for _, j := range page.Jobs {
+		rows = append(rows, []string{
+			j.ID, j.Type, j.Queue, string(j.Status),
+			fmt.Sprint(j.Priority), fmt.Sprintf("%d/%d", j.Attempts, j.MaxAttempts),
+			j.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	if err := p.table("ID\tTYPE\tQUEUE\tSTATUS\tPRIORITY\tATTEMPTS\tCREATED", rows); err != nil {
+		return err
+	}
+	if page.NextCursor != "" {
+		fmt.Fprintf(p.w, "\nmore: -cursor %s\n", page.NextCursor)
+	}
+	return nil
+}
+
+func (p *printer) event(e client.JobEvent) error {
+	if p.json {
+		// One event per line so the stream can be piped into jq.
+		return json.NewEncoder(p.w).Encode(e)
+	}
+	msg := ""
+	if e.Message != nil {
+		msg = " " + *e.Message
+	}
+	_, err := fmt.Fprintf(p.w, "%s  %-8d %-28s %s %s/%s %s attempts=%d%s\n",
+		e.CreatedAt.Format(time.RFC3339), e.ID, e.Type, e.JobID, e.Queue, e.JobType, e.Status, e.Attempts, msg)
+	return err
+}
+
+func (p *printer) retried(r *client.RetryJobsResult) error {
+	if p.json {
+		return p.encode(r)
+	}
+	fmt.Fprintf(p.w, "requeued %d jobs, skipped %d\n", len(r.Retried), r.Skipped)
+	if r.QuotaExceeded {
+		fmt.Fprintln(p.w, "stopped early: tenant queued quota reached")
+	}
+	return nil
+}
+
+func (p *printer) queueState(queue string, paused bool) error {
+	if p.json {
+		return p.encode(map[string]any{"queue": queue, "paused": paused})
+	}
+	state := "resumed"
+	if paused {
+		state = "paused"
+	}
+	_, err := fmt.Fprintf(p.w, "queue %s %s\n", queue, state)
+	return err
+}
+
+// statusColumns is the column order of the stats table.
+var statusColumns = []client.JobStatus{
+	"PENDING", "BLOCKED", "RUNNING", "SUCCESS", "FAILED", "CANCELLED",
+	"COMPENSATING", "COMPENSATED", "COMPENSATION_FAILED",
+}
+
+func (p *printer) stats(queues []client.QueueStats) error {
+	if p.json {
+		return p.encode(map[string]any{"queues": queues})
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Queue < queues[j].Queue })
+
+	header := []string{"QUEUE", "PAUSED"}
+	for _, s := range statusColumns {
+		header = append(header, string(s))
+	}
+	totals := make([]int, len(statusColumns))
+	rows := make([][]string, 0, len(queues)+1)
+	for _, q := range queues {
+		paused := "no"
+		if q.Paused {
+			paused = "yes"
+		}
+		row := []string{q.Queue, paused}
+		for i, s := range statusColumns {
+			row = append(row, fmt.Sprint(q.Counts[s]))
+			totals[i] += q.Counts[s]
+		}
+		rows = append(rows, row)
+	}
+	total := []string{"TOTAL", ""}
+	for _, n := range totals {
+		total = append(total, fmt.Sprint(n))
+	}
+	rows = append(rows, total)
+	return p.table(strings.Join(header, "\t"), rows)
+}
+
+func fmtTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
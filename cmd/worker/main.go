@@ -10,8 +10,13 @@ import (
 	"syscall"
 	"time"
 
+	"task-scheduler/internal/blob"
 	"task-scheduler/internal/config"
+	"task-scheduler/internal/domain"
+	"task-scheduler/internal/keyring"
 	mysqlrepo "task-scheduler/internal/repo/mysql"
+	"task-scheduler/internal/service"
+	"task-scheduler/internal/webhook"
 	"task-scheduler/internal/worker"
 )
 
@@ -27,7 +32,25 @@ func main() {
 	}
 	defer db.Close()
 
-	repo := mysqlrepo.NewJobRepo(db)
+	// Jobs the worker enqueues (batch callbacks) are sealed like any other.
+	var kr *keyring.Keyring
+	if cfg.PayloadKeyringFile != "" {
+		if kr, err = keyring.Load(cfg.PayloadKeyringFile); err != nil {
+			log.Fatalf("load payload keyring: %v", err)
+		}
+	}
+	blobs, err := blob.Open(cfg.Blob)
+	if err != nil {
+		log.Fatalf("open blob store: %v", err)
+	}
+	payloads := mysqlrepo.PayloadOptions{
+		CompressMin:   cfg.CompressMinBytes,
+		Keyring:       kr,
+		Blobs:         blobs,
+		BlobThreshold: cfg.BlobThresholdBytes,
+	}
+
+	repo := mysqlrepo.NewJobRepo(db, payloads)
 
 	rootCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -59,8 +82,91 @@ func main() {
 	}
 
 	runner := worker.NewRunner(repo, backoff, failRate, log.Default())
+	runner.CompensationMaxAttempts = envInt("COMPENSATION_MAX_ATTEMPTS", 5)
+
+	static, err := service.LoadSchemaFile(cfg.SchemasFile)
+	if err != nil {
+		log.Fatalf("load job schemas: %v", err)
+	}
+	schemas, err := service.NewSchemaRegistry(mysqlrepo.NewPayloadSchemaRepo(db), static, cfg.SchemaCacheTTL)
+	if err != nil {
+		log.Fatalf("load job schemas: %v", err)
+	}
+	runner.Schemas = schemas
+	runner.Keyring = kr
+	runner.Blobs = blobs
+	// Jobs running longer than one lease keep it by heartbeating every third
+	// of it, so they are neither claimed again nor reaped.
+	lease := time.Duration(cfg.LeaseSeconds) * time.Second
+	runner.Heartbeat = worker.NewHeartbeatManager(repo, cfg.WorkerID, lease, lease/3)
+	// Job handlers are registered here with runner.Handlers.Register; types
+	// without a handler are simulated using FAIL_RATE.
 	pool := worker.NewPool(rootCtx, runner, poolSize, queueSize)
 
+	// Completion webhooks share the job backoff settings.
+	if secret := os.Getenv("WEBHOOK_SECRET"); secret != "" {
+		dispatcher := worker.NewWebhookDispatcher(
+			mysqlrepo.NewWebhookRepo(db),
+			[]byte(secret),
+			backoff,
+			envInt("WEBHOOK_MAX_ATTEMPTS", 8),
+			cfg.WorkerID,
+			log.Default(),
+		)
+		if os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true" {
+			log.Println("WEBHOOK_ALLOW_PRIVATE set: webhooks may reach private and loopback addresses")
+			dispatcher.Client = webhook.NewClient(10*time.Second, true)
+		}
+		go dispatcher.Run(rootCtx, cfg.PollInterval)
+	} else {
+		log.Println("WEBHOOK_SECRET not set: completion webhooks will not be delivered")
+	}
+
+	// Maintenance that must run once per cluster runs on the leader; any
+	// worker can be elected.
+	elector := worker.NewElector(
+		mysqlrepo.NewLeaseRepo(db),
+		"leader",
+		cfg.WorkerID,
+		time.Duration(envInt("LEADER_LEASE_SECONDS", 15))*time.Second,
+		log.Default(),
+	)
+
+	reaper := worker.NewReaper(repo, runner, cfg.WorkerID, log.Default())
+	reaper.Lease = lease
+	// Running jobs heartbeat; the grace covers a few missed beats.
+	reaper.Grace = time.Duration(envInt("REAPER_GRACE_SECONDS", cfg.LeaseSeconds)) * time.Second
+	reaper.Limits = mysqlrepo.NewConcurrencyLimitRepo(db)
+	elector.Go("reaper", func(ctx context.Context) {
+		reaper.Run(ctx, time.Duration(envInt("REAPER_INTERVAL_SECONDS", 30))*time.Second)
+	})
+
+	if path := os.Getenv("RETENTION_FILE"); path != "" {
+		rules, err := worker.LoadRetentionRules(path)
+		if err != nil {
+			log.Fatalf("load retention rules: %v", err)
+		}
+		sweeper := worker.NewRetentionSweeper(
+			repo,
+			mysqlrepo.NewRetentionRepo(db, payloads),
+			rules,
+			cfg.WorkerID,
+			log.Default(),
+		)
+		sweeper.ArchiveDir = os.Getenv("RETENTION_ARCHIVE_DIR")
+		sweeper.BatchSize = envInt("RETENTION_BATCH", 500)
+		sweeper.Pause = time.Duration(envInt("RETENTION_PAUSE_MS", 200)) * time.Millisecond
+		elector.Go("retention", func(ctx context.Context) {
+			sweeper.Run(ctx, time.Duration(envInt("RETENTION_INTERVAL_SECONDS", 3600))*time.Second)
+		})
+	}
+
+	electorDone := make(chan struct{})
+	go func() {
+		defer close(electorDone)
+		elector.Run(rootCtx)
+	}()
+
 	log.Printf("worker started id=%s poll=%s pool=%d queue=%d fail_rate=%.2f",
 		cfg.WorkerID, cfg.PollInterval, poolSize, queueSize, failRate,
 	)
@@ -74,6 +180,8 @@ func main() {
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 			_ = pool.Stop(ctx)
+			// Let the leader stop its tasks and hand over the lease.
+			<-electorDone
 			log.Println("worker stopped")
 			return
 
@@ -84,7 +192,7 @@ func main() {
 				rootCtx,
 				cfg.WorkerID,
 				10,
-				time.Duration(cfg.LeaseSeconds)*time.Second,
+				lease,
 				now,
 			)
 			if err != nil {
@@ -93,16 +201,16 @@ func main() {
 			}
 
 			for _, j := range claimed {
-				ok := pool.Submit(worker.Job{
-					ID:          j.ID,
-					Attempts:    j.Attempts,
-					MaxAttempts: j.MaxAttempts,
-				})
+				ok := pool.Submit(worker.JobFrom(j))
 
 				if !ok {
 					// Backpressure: reschedule quickly and release lease.
 					next := time.Now().Add(250 * time.Millisecond)
-					_ = repo.MarkFailure(rootCtx, j.ID, j.Attempts, &next, "queue full - rescheduled", false, nil)
+					if j.Status == domain.StatusCompensating {
+						_ = repo.MarkCompensationRetry(rootCtx, j.ID, j.CompensationAttempts, next, "queue full - rescheduled")
+					} else {
+						_ = repo.MarkFailure(rootCtx, j.ID, j.Attempts, &next, "queue full - rescheduled", false, nil)
+					}
 					log.Printf("queue full: rescheduled job %s", j.ID)
 				}
 			}
@@ -1,18 +1,52 @@
 CREATE TABLE IF NOT EXISTS jobs (
     id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL DEFAULT 'default',
     type VARCHAR(50) NOT NULL,
+    queue VARCHAR(64) NOT NULL DEFAULT 'default',
     payload JSON NOT NULL,
+    -- identity, or gzip: payload is a JSON string of base64 gzip data
+    payload_encoding VARCHAR(16) NOT NULL DEFAULT 'identity',
+    -- Blob-store key of an offloaded payload; payload is then JSON null
+    payload_ref VARCHAR(255) NULL,
+    -- Shape of payload; workers upcast older versions before running
+    payload_version INT NOT NULL DEFAULT 1,
 
-    status ENUM('PENDING', 'RUNNING', 'SUCCESS', 'FAILED') 
+    status ENUM('PENDING', 'BLOCKED', 'RUNNING', 'SUCCESS', 'FAILED', 'CANCELLED',
+                'COMPENSATING', 'COMPENSATED', 'COMPENSATION_FAILED')
         NOT NULL DEFAULT 'PENDING',
+    priority INT NOT NULL DEFAULT 0,
 
     -- Retry mechanism
     attempts INT NOT NULL DEFAULT 0,
     max_attempts INT NOT NULL DEFAULT 3,
     next_run_at TIMESTAMP NULL,
+    compensation_attempts INT NOT NULL DEFAULT 0,
 
     -- Idempotency
-    idempotency_key VARCHAR(255) UNIQUE,
+    idempotency_key VARCHAR(255) NULL,
+    idempotency_fingerprint CHAR(64) NULL,
+    idempotency_expires_at TIMESTAMP(6) NULL,
+
+    -- Completion webhook
+    callback_url VARCHAR(2048) NULL,
+
+    -- Cluster-wide concurrency group (see concurrency_limits)
+    concurrency_key VARCHAR(255) NULL,
+
+    -- Content-based uniqueness (see job_unique_keys)
+    unique_key VARCHAR(255) NULL,
+
+    -- Fan-out batch (see batches)
+    batch_id VARCHAR(36) NULL,
+
+    -- Dependencies: BLOCKED until pending_parents reaches 0 (see job_dependencies)
+    workflow_id VARCHAR(36) NULL,
+    workflow_node VARCHAR(100) NULL,
+    on_parent_failure ENUM('cascade', 'run') NOT NULL DEFAULT 'cascade',
+    pending_parents INT NOT NULL DEFAULT 0,
+
+    -- Audit
+    api_key_id VARCHAR(36) NULL,
 
     -- Distributed locking (CRITICAL)
     locked_by VARCHAR(64) NULL,
@@ -29,20 +63,256 @@ CREATE TABLE IF NOT EXISTS jobs (
         ON UPDATE CURRENT_TIMESTAMP,
 
     INDEX idx_pick (status, next_run_at, locked_until),
-    INDEX idx_idempotency_key (idempotency_key),
+    INDEX idx_type_version (status, type, payload_version),
+    INDEX idx_retention (status, completed_at),
+    INDEX idx_tenant_pick (tenant_id, status, priority, next_run_at),
+    INDEX idx_concurrency_key (type, concurrency_key, status),
+    INDEX idx_workflow (workflow_id),
+    INDEX idx_batch (batch_id),
+    UNIQUE KEY uq_tenant_idempotency_key (tenant_id, idempotency_key),
     INDEX idx_created_at (created_at)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
--- Exactly-once execution guard per job step
+-- Exactly-once execution guard per job step; output memoizes handler steps
 CREATE TABLE IF NOT EXISTS job_executions (
     job_id VARCHAR(36) NOT NULL,
     step_key VARCHAR(100) NOT NULL,
     result_hash VARCHAR(64) NULL,
+    output JSON NULL,
+    output_encoding VARCHAR(16) NOT NULL DEFAULT 'identity',
+    seq BIGINT NOT NULL AUTO_INCREMENT, -- completion order, for reverse compensation
     executed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 
     PRIMARY KEY (job_id, step_key),
+    UNIQUE KEY uq_seq (seq),
     INDEX idx_executed_at (executed_at),
 
     CONSTRAINT fk_job_exec_job
       FOREIGN KEY (job_id) REFERENCES jobs(id)
       ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Durable lifecycle log; id is the sequence used for SSE Last-Event-ID resume
+CREATE TABLE IF NOT EXISTS job_events (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    job_id VARCHAR(36) NOT NULL,
+    tenant_id VARCHAR(64) NOT NULL,
+    job_type VARCHAR(50) NOT NULL,
+    queue VARCHAR(64) NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INT NOT NULL,
+    message TEXT NULL,
+    created_at TIMESTAMP(6) DEFAULT CURRENT_TIMESTAMP(6),
+    -- Position in the stream, assigned by readers after commit (NULL until then).
+    -- AUTO_INCREMENT ids follow insert order, not commit order, so they cannot
+    -- serve as a cursor.
+    seq BIGINT UNSIGNED NULL,
+
+    UNIQUE KEY uq_events_seq (seq),
+    INDEX idx_events_tenant (tenant_id, seq),
+    INDEX idx_events_job (job_id, id),
+    INDEX idx_events_type (tenant_id, job_type, seq),
+    INDEX idx_events_queue (tenant_id, queue, seq)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Last seq handed out to job_events; the row lock serialises sequencing.
+CREATE TABLE IF NOT EXISTS event_sequence (
+    id TINYINT UNSIGNED PRIMARY KEY,
+    last_seq BIGINT UNSIGNED NOT NULL
+) ENGINE=InnoDB;
+
+INSERT IGNORE INTO event_sequence (id, last_seq) VALUES (1, 0);
+
+-- Outbox of completion webhooks (one row per terminal transition with a callback_url)
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    job_id VARCHAR(36) NOT NULL,
+    tenant_id VARCHAR(64) NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    body JSON NOT NULL,
+
+    status ENUM('PENDING', 'DELIVERED', 'FAILED') NOT NULL DEFAULT 'PENDING',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    last_status_code INT NULL,
+    last_error TEXT NULL,
+    delivered_at TIMESTAMP NULL,
+
+    locked_by VARCHAR(64) NULL,
+    locked_until TIMESTAMP NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        ON UPDATE CURRENT_TIMESTAMP,
+
+    INDEX idx_delivery_pick (status, next_attempt_at),
+    INDEX idx_delivery_job (job_id),
+    INDEX idx_delivery_tenant (tenant_id, status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- API keys (only the SHA-256 of the key is stored)
+CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes JSON NOT NULL,
+    allowed_types JSON NULL,
+    allowed_queues JSON NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP NULL,
+
+    INDEX idx_api_keys_tenant (tenant_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Per-tenant limits enforced by CreateJob (max_queued) and ClaimJobs (max_running)
+CREATE TABLE IF NOT EXISTS tenant_quotas (
+    tenant_id VARCHAR(64) PRIMARY KEY,
+    max_running INT NULL,
+    max_queued INT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Cluster-wide token buckets checked by ClaimJobs; queue = '' applies to every queue
+CREATE TABLE IF NOT EXISTS rate_limits (
+    job_type VARCHAR(50) NOT NULL,
+    queue VARCHAR(64) NOT NULL DEFAULT '',
+    rate_per_sec DOUBLE NOT NULL,
+    burst INT NOT NULL,
+    tokens DOUBLE NOT NULL,
+    refilled_at TIMESTAMP(6) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (job_type, queue)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Cluster-wide caps on RUNNING jobs per type and per concurrency key.
+-- Claims lock the rows of the capped types they pick.
+CREATE TABLE IF NOT EXISTS concurrency_limits (
+    job_type VARCHAR(50) PRIMARY KEY,
+    max_running INT NULL,
+    max_per_key INT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- One row per (type, concurrency_key) seen by claims. A claim locks the row
+-- while it counts the key's RUNNING jobs, instead of locking the jobs; the
+-- leader prunes rows unused for a day.
+CREATE TABLE IF NOT EXISTS concurrency_keys (
+    job_type VARCHAR(50) NOT NULL,
+    concurrency_key VARCHAR(255) NOT NULL,
+    locked_at TIMESTAMP(6) NOT NULL,
+
+    PRIMARY KEY (job_type, concurrency_key),
+    INDEX idx_locked_at (locked_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Latest job per unique key. Creates lock the row to serialize duplicates;
+-- whether the job still blocks new ones is decided from its status/age.
+CREATE TABLE IF NOT EXISTS job_unique_keys (
+    tenant_id VARCHAR(64) NOT NULL,
+    job_type VARCHAR(50) NOT NULL,
+    unique_key VARCHAR(255) NOT NULL,
+    job_id VARCHAR(36) NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (tenant_id, job_type, unique_key)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Job DAG edges: job_id runs after parent_id
+CREATE TABLE IF NOT EXISTS job_dependencies (
+    job_id VARCHAR(36) NOT NULL,
+    parent_id VARCHAR(36) NOT NULL,
+
+    PRIMARY KEY (job_id, parent_id),
+    INDEX idx_parent (parent_id),
+
+    CONSTRAINT fk_dep_job
+      FOREIGN KEY (job_id) REFERENCES jobs(id)
+      ON DELETE CASCADE,
+    CONSTRAINT fk_dep_parent
+      FOREIGN KEY (parent_id) REFERENCES jobs(id)
+      ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Workflows group the jobs of one submitted DAG (jobs.workflow_id)
+CREATE TABLE IF NOT EXISTS workflows (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NULL,
+    api_key_id VARCHAR(36) NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    INDEX idx_tenant_created (tenant_id, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Fan-out batches; counters are updated in the transaction that finishes each child
+CREATE TABLE IF NOT EXISTS batches (
+    id VARCHAR(36) PRIMARY KEY,
+    tenant_id VARCHAR(64) NOT NULL,
+    total INT NOT NULL,
+    pending INT NOT NULL,
+    succeeded INT NOT NULL DEFAULT 0,
+    failed INT NOT NULL DEFAULT 0,
+    api_key_id VARCHAR(36) NULL,
+
+    -- Optional job enqueued once every child is terminal
+    callback_type VARCHAR(50) NULL,
+    callback_queue VARCHAR(64) NULL,
+    callback_payload JSON NULL,
+    callback_max_attempts INT NULL,
+    callback_job_id VARCHAR(36) NULL,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP NULL,
+
+    INDEX idx_tenant_created (tenant_id, created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Queues whose jobs are not claimed until resumed
+CREATE TABLE IF NOT EXISTS paused_queues (
+    tenant_id VARCHAR(64) NOT NULL,
+    queue VARCHAR(64) NOT NULL,
+    paused_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (tenant_id, queue)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- JSON Schema each job type's payload must match (set via /admin/job-schemas)
+CREATE TABLE IF NOT EXISTS job_schemas (
+    job_type VARCHAR(50) PRIMARY KEY,
+    schema_doc JSON NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+        ON UPDATE CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- Named leases for cluster singletons: the holder owns the name until
+-- locked_until, and must renew before then
+CREATE TABLE IF NOT EXISTS leases (
+    name VARCHAR(64) PRIMARY KEY,
+    -- worker.Elector holders are "<WORKER_ID>/<host>/<pid>/<random>"
+    holder VARCHAR(255) NOT NULL,
+    locked_until TIMESTAMP(6) NOT NULL,
+    acquired_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+
+-- One row per retention sweep (see RETENTION_FILE); GET /admin/retention
+-- shows the latest
+CREATE TABLE IF NOT EXISTS retention_runs (
+    id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    worker_id VARCHAR(64) NOT NULL,
+    started_at TIMESTAMP(6) NOT NULL,
+    finished_at TIMESTAMP(6) NOT NULL,
+    deleted INT NOT NULL,
+    archived INT NOT NULL,
+    archive_file VARCHAR(1024) NULL,
+    rules JSON NOT NULL,
+    error TEXT NULL,
+
+    INDEX idx_started_at (started_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
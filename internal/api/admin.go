@@ -0,0 +1,353 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"task-scheduler/internal/auth"
+	"task-scheduler/internal/domain"
+)
+
+type createAPIKeyReq struct {
+	TenantID      string         `json:"tenant_id"`
+	Name          string         `json:"name"`
+	Scopes        []domain.Scope `json:"scopes"`
+	AllowedTypes  []string       `json:"allowed_types"`
+	AllowedQueues []string       `json:"allowed_queues"`
+}
+
+type createAPIKeyResp struct {
+	// Key is the plaintext secret. It is only ever returned here.
+	Key    string         `json:"key"`
+	APIKey *domain.APIKey `json:"api_key"`
+}
+
+// CreateAPIKey serves POST /admin/api-keys.
+func (h *Handlers) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req createAPIKeyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badJSON(err))
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Scopes) == 0 {
+		writeError(w, r, invalid("name_and_scopes_required", "name and scopes are required",
+			fieldError{"name", "required"}, fieldError{"scopes", "at least one scope"}))
+		return
+	}
+	caller := principalFrom(r.Context())
+	for _, s := range req.Scopes {
+		if !domain.ValidScope(s) {
+			writeError(w, r, invalid("invalid_scope", "unknown scope "+string(s),
+				fieldError{"scopes", "unknown scope " + string(s)}))
+			return
+		}
+		// Nobody can mint a key more powerful than their own.
+		if !caller.HasScope(s) {
+			writeError(w, r, errForbidden)
+			return
+		}
+	}
+
+	// Nor one that reaches job types or queues that theirs cannot.
+	types, queues, ok := caller.Within(req.AllowedTypes, req.AllowedQueues)
+	if !ok {
+		writeError(w, r, errForbidden)
+		return
+	}
+
+	req.TenantID = strings.TrimSpace(req.TenantID)
+	switch {
+	case req.TenantID == "":
+		req.TenantID = caller.TenantID
+	case req.TenantID != caller.TenantID && !caller.HasScope(domain.ScopeTenantsAdmin):
+		writeError(w, r, errForbidden)
+		return
+	}
+
+	plain, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	key := domain.APIKey{
+		ID:            newID(),
+		TenantID:      req.TenantID,
+		Name:          req.Name,
+		Prefix:        prefix,
+		Scopes:        req.Scopes,
+		AllowedTypes:  types,
+		AllowedQueues: queues,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err := h.APIKeys.CreateAPIKey(r.Context(), key, hash); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(createAPIKeyResp{Key: plain, APIKey: &key})
+}
+
+// ListAPIKeys serves GET /admin/api-keys.
+func (h *Handlers) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.APIKeys.ListAPIKeys(r.Context(), scopedTenant(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if keys == nil {
+		keys = []domain.APIKey{}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"api_keys": keys})
+}
+
+// RevokeAPIKey serves DELETE /admin/api-keys/{id}.
+func (h *Handlers) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/admin/api-keys/")
+	if id == "" || strings.Contains(id, "/") {
+		notFound(w, r)
+		return
+	}
+
+	// Operators may revoke keys of any tenant.
+	caller := principalFrom(r.Context())
+	tenant := caller.TenantID
+	if caller.HasScope(domain.ScopeTenantsAdmin) {
+		tenant = ""
+	}
+
+	err := h.APIKeys.RevokeAPIKey(r.Context(), tenant, id, time.Now())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type setQuotaReq struct {
+	MaxRunning *int `json:"max_running"`
+	MaxQueued  *int `json:"max_queued"`
+}
+
+// ListQuotas serves GET /admin/tenants.
+func (h *Handlers) ListQuotas(w http.ResponseWriter, r *http.Request) {
+	quotas, err := h.Tenants.ListQuotas(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if quotas == nil {
+		quotas = []domain.TenantQuota{}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"quotas": quotas})
+}
+
+// GetQuota serves GET /admin/tenants/{id}/quota.
+func (h *Handlers) GetQuota(w http.ResponseWriter, r *http.Request) {
+	tenant := tenantPath(r.URL.Path)
+	if tenant == "" {
+		notFound(w, r)
+		return
+	}
+
+	q, err := h.Tenants.GetQuota(r.Context(), tenant)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if q == nil {
+		// No row means unlimited.
+		q = &domain.TenantQuota{TenantID: tenant}
+	}
+	_ = json.NewEncoder(w).Encode(q)
+}
+
+// SetQuota serves PUT /admin/tenants/{id}/quota. Omitted/null fields mean unlimited.
+func (h *Handlers) SetQuota(w http.ResponseWriter, r *http.Request) {
+	tenant := tenantPath(r.URL.Path)
+	if tenant == "" {
+		notFound(w, r)
+		return
+	}
+
+	var req setQuotaReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badJSON(err))
+		return
+	}
+	if (req.MaxRunning != nil && *req.MaxRunning < 0) || (req.MaxQueued != nil && *req.MaxQueued < 0) {
+		writeError(w, r, invalid("invalid_quota", "quotas must not be negative"))
+		return
+	}
+
+	q := domain.TenantQuota{TenantID: tenant, MaxRunning: req.MaxRunning, MaxQueued: req.MaxQueued}
+	if err := h.Tenants.SetQuota(r.Context(), q); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.GetQuota(w, r)
+}
+
+// tenantPath extracts {id} from /admin/tenants/{id}/quota.
+func tenantPath(path string) string {
+	rest := strings.TrimPrefix(path, "/admin/tenants/")
+	id, action, _ := strings.Cut(rest, "/")
+	if action != "quota" {
+		return ""
+	}
+	return id
+}
+
+type setRateLimitReq struct {
+	RatePerSec float64 `json:"rate_per_sec"`
+	Burst      int     `json:"burst"`
+}
+
+// ListRateLimits serves GET /admin/rate-limits.
+func (h *Handlers) ListRateLimits(w http.ResponseWriter, r *http.Request) {
+	limits, err := h.RateLimits.ListRateLimits(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if limits == nil {
+		limits = []domain.RateLimit{}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"rate_limits": limits})
+}
+
+// SetRateLimit serves PUT /admin/rate-limits/{type}[?queue=q].
+// Changes apply to the next claim on every worker.
+func (h *Handlers) SetRateLimit(w http.ResponseWriter, r *http.Request) {
+	jobType := strings.TrimPrefix(r.URL.Path, "/admin/rate-limits/")
+	if jobType == "" || strings.Contains(jobType, "/") {
+		notFound(w, r)
+		return
+	}
+
+	var req setRateLimitReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badJSON(err))
+		return
+	}
+	if req.Burst == 0 {
+		req.Burst = int(math.Max(1, math.Ceil(req.RatePerSec)))
+	}
+
+	rl := domain.RateLimit{
+		JobType:    jobType,
+		Queue:      strings.TrimSpace(r.URL.Query().Get("queue")),
+		RatePerSec: req.RatePerSec,
+		Burst:      req.Burst,
+	}
+	err := h.RateLimits.SetRateLimit(r.Context(), rl)
+	switch {
+	case errors.Is(err, domain.ErrInvalidInput):
+		writeError(w, r, recode(err, "invalid_rate_limit"))
+		return
+	case err != nil:
+		writeError(w, r, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(rl)
+}
+
+// DeleteRateLimit serves DELETE /admin/rate-limits/{type}[?queue=q].
+func (h *Handlers) DeleteRateLimit(w http.ResponseWriter, r *http.Request) {
+	jobType := strings.TrimPrefix(r.URL.Path, "/admin/rate-limits/")
+	if jobType == "" || strings.Contains(jobType, "/") {
+		notFound(w, r)
+		return
+	}
+
+	err := h.RateLimits.DeleteRateLimit(r.Context(), jobType, strings.TrimSpace(r.URL.Query().Get("queue")))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type setConcurrencyLimitReq struct {
+	MaxRunning *int `json:"max_running"`
+	MaxPerKey  *int `json:"max_per_key"`
+}
+
+// ListConcurrencyLimits serves GET /admin/concurrency-limits.
+func (h *Handlers) ListConcurrencyLimits(w http.ResponseWriter, r *http.Request) {
+	limits, err := h.ConcurrencyLimits.ListConcurrencyLimits(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if limits == nil {
+		limits = []domain.ConcurrencyLimit{}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"concurrency_limits": limits})
+}
+
+// SetConcurrencyLimit serves PUT /admin/concurrency-limits/{type}.
+// Lowering a cap never preempts RUNNING jobs; it only holds back new claims.
+func (h *Handlers) SetConcurrencyLimit(w http.ResponseWriter, r *http.Request) {
+	jobType := strings.TrimPrefix(r.URL.Path, "/admin/concurrency-limits/")
+	if jobType == "" || strings.Contains(jobType, "/") {
+		notFound(w, r)
+		return
+	}
+
+	var req setConcurrencyLimitReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badJSON(err))
+		return
+	}
+
+	l := domain.ConcurrencyLimit{JobType: jobType, MaxRunning: req.MaxRunning, MaxPerKey: req.MaxPerKey}
+	err := h.ConcurrencyLimits.SetConcurrencyLimit(r.Context(), l)
+	switch {
+	case errors.Is(err, domain.ErrInvalidInput):
+		writeError(w, r, recode(err, "invalid_concurrency_limit"))
+		return
+	case err != nil:
+		writeError(w, r, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(l)
+}
+
+// DeleteConcurrencyLimit serves DELETE /admin/concurrency-limits/{type}.
+func (h *Handlers) DeleteConcurrencyLimit(w http.ResponseWriter, r *http.Request) {
+	jobType := strings.TrimPrefix(r.URL.Path, "/admin/concurrency-limits/")
+	if jobType == "" || strings.Contains(jobType, "/") {
+		notFound(w, r)
+		return
+	}
+
+	err := h.ConcurrencyLimits.DeleteConcurrencyLimit(r.Context(), jobType)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RetentionStatus serves GET /admin/retention: the last retention sweep,
+// or null if none has run.
+func (h *Handlers) RetentionStatus(w http.ResponseWriter, r *http.Request) {
+	run, err := h.Retention.LastRetentionRun(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"last_run": run})
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"task-scheduler/internal/auth"
+	"task-scheduler/internal/domain"
+	"task-scheduler/internal/repo"
+)
+
+type principalKey struct{}
+
+// bootstrapKeyID identifies requests made with ADMIN_API_KEY.
+const bootstrapKeyID = "bootstrap"
+
+// principalFrom returns the API key that authenticated the request.
+// Only nil for unauthenticated paths such as /healthz.
+func principalFrom(ctx context.Context) *domain.APIKey {
+	p, _ := ctx.Value(principalKey{}).(*domain.APIKey)
+	return p
+}
+
+// authMW resolves the caller's API key from "Authorization: Bearer <key>"
+// or "X-API-Key". bootstrapKey, if set, is accepted as a jobs:admin key so
+// the first real keys can be minted. The key's tenant becomes the request's tenant.
+func authMW(keys repo.APIKeyRepository, bootstrapKey string, next http.Handler) http.Handler {
+	var bootstrapHash string
+	if bootstrapKey != "" {
+		bootstrapHash = auth.HashKey(bootstrapKey)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		plain := bearerToken(r)
+		if plain == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="task-scheduler"`)
+			writeError(w, r, apiErr(http.StatusUnauthorized, "unauthorized", "missing API key"))
+			return
+		}
+		hash := auth.HashKey(plain)
+
+		var key *domain.APIKey
+		if bootstrapHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(bootstrapHash)) == 1 {
+			key = &domain.APIKey{
+				ID:       bootstrapKeyID,
+				TenantID: domain.DefaultTenant,
+				Name:     bootstrapKeyID,
+				Scopes:   []domain.Scope{domain.ScopeJobsAdmin, domain.ScopeTenantsAdmin},
+			}
+		} else {
+			k, err := keys.GetAPIKeyByHash(r.Context(), hash)
+			if err != nil {
+				log.Printf("auth: key lookup failed: %v", err)
+				writeError(w, r, apiErr(http.StatusServiceUnavailable, "auth_unavailable", "API keys cannot be checked right now"))
+				return
+			}
+			key = k
+		}
+		if key == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="task-scheduler", error="invalid_token"`)
+			writeError(w, r, apiErr(http.StatusUnauthorized, "unauthorized", "invalid or revoked API key"))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, key)))
+	})
+}
+
+// requireScope rejects callers whose key lacks scope.
+func requireScope(scope domain.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := principalFrom(r.Context())
+		if p == nil || !p.HasScope(scope) {
+			writeError(w, r, apiErr(http.StatusForbidden, "forbidden", "the API key lacks the "+string(scope)+" scope"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// scopedTenant returns the tenant an admin listing should be limited to:
+// the caller's own tenant, or for operators the optional ?tenant_id= ("" = all).
+func scopedTenant(r *http.Request) string {
+	p := principalFrom(r.Context())
+	if p.HasScope(domain.ScopeTenantsAdmin) {
+		return strings.TrimSpace(r.URL.Query().Get("tenant_id"))
+	}
+	return p.TenantID
+}
+
+func bearerToken(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(h, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
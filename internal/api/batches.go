@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"task-scheduler/internal/domain"
+)
+
+type createBatchReq struct {
+	Jobs     []batchJobReq `json:"jobs"`
+	Callback *batchJobReq  `json:"callback"`
+}
+
+type batchJobReq struct {
+	Type           string          `json:"type"`
+	Queue          string          `json:"queue"`
+	Payload        json.RawMessage `json:"payload"`
+	MaxAttempts    int             `json:"max_attempts"`
+	ConcurrencyKey string          `json:"concurrency_key"`
+	PayloadVersion int             `json:"payload_version"`
+}
+
+func (j batchJobReq) spec(id string, callerID *string) domain.JobSpec {
+	spec := domain.JobSpec{
+		ID:             id,
+		Type:           strings.TrimSpace(j.Type),
+		Queue:          strings.TrimSpace(j.Queue),
+		Payload:        j.Payload,
+		PayloadVersion: j.PayloadVersion,
+		MaxAttempts:    j.MaxAttempts,
+		APIKeyID:       callerID,
+	}
+	if spec.Queue == "" {
+		spec.Queue = domain.DefaultQueue
+	}
+	if ck := strings.TrimSpace(j.ConcurrencyKey); ck != "" {
+		spec.ConcurrencyKey = &ck
+	}
+	return spec
+}
+
+// CreateBatch serves POST /batches: all jobs are created in one transaction,
+// or none are.
+func (h *Handlers) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var req createBatchReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badJSON(err))
+		return
+	}
+	if len(req.Jobs) == 0 || len(req.Jobs) > domain.MaxBatchSize {
+		writeError(w, r, invalid("invalid_batch_size", fmt.Sprintf("a batch has 1 to %d jobs", domain.MaxBatchSize),
+			fieldError{"jobs", fmt.Sprintf("must have 1 to %d items", domain.MaxBatchSize)}))
+		return
+	}
+
+	caller := principalFrom(r.Context())
+	batchID := newID()
+	spec := domain.BatchSpec{ID: batchID, TenantID: caller.TenantID, APIKeyID: &caller.ID}
+	spec.Jobs = make([]domain.JobSpec, 0, len(req.Jobs))
+	for i, j := range req.Jobs {
+		js := j.spec(fmt.Sprintf("%s-%d", batchID, i), &caller.ID)
+		if err := h.Jobs.Prepare(r.Context(), &js); err != nil {
+			writeError(w, r, atField(err, fmt.Sprintf("jobs[%d]", i)))
+			return
+		}
+		if !caller.Allows(js.Type, js.Queue) {
+			writeError(w, r, errForbidden)
+			return
+		}
+		spec.Jobs = append(spec.Jobs, js)
+	}
+	if req.Callback != nil {
+		cb := req.Callback.spec("", &caller.ID)
+		if cb.Type == "" {
+			writeError(w, r, invalid("invalid_callback", "callback needs a type",
+				fieldError{"callback.type", "required"}))
+			return
+		}
+		if err := h.Jobs.PrepareCallback(&cb); err != nil {
+			writeError(w, r, atField(err, "callback"))
+			return
+		}
+		if !caller.Allows(cb.Type, cb.Queue) {
+			writeError(w, r, errForbidden)
+			return
+		}
+		spec.Callback = &cb
+	}
+
+	batch, err := h.Batches.CreateBatch(r.Context(), spec)
+	switch {
+	case errors.Is(err, domain.ErrInvalidInput):
+		writeError(w, r, recode(err, "invalid_batch"))
+		return
+	case err != nil:
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(batch)
+}
+
+// GetBatch serves GET /batches/{id}.
+func (h *Handlers) GetBatch(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/batches/")
+	if id == "" || strings.Contains(id, "/") {
+		notFound(w, r)
+		return
+	}
+
+	batch, err := h.Batches.GetBatch(r.Context(), principalFrom(r.Context()).TenantID, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if batch == nil {
+		writeError(w, r, apiErr(http.StatusNotFound, "not_found", "batch not found"))
+		return
+	}
+	_ = json.NewEncoder(w).Encode(batch)
+}
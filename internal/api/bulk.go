@@ -0,0 +1,133 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"task-scheduler/internal/domain"
+)
+
+// maxBulkItems caps the number of jobs accepted by one POST /jobs:bulk.
+const maxBulkItems = 10_000
+
+// bulkJobReq is one item of a bulk create: a createJobReq plus the
+// idempotency key that CreateJob takes from the Idempotency-Key header.
+type bulkJobReq struct {
+	createJobReq
+	IdempotencyKey string `json:"idempotency_key"`
+}
+
+type bulkItemResp struct {
+	Index  int        `json:"index"`
+	Status string     `json:"status"`
+	JobID  string     `json:"job_id,omitempty"`
+	Error  *errorBody `json:"error,omitempty"`
+}
+
+type bulkResp struct {
+	Results  []bulkItemResp `json:"results"`
+	Created  int            `json:"created"`
+	Replayed int            `json:"replayed"`
+	Failed   int            `json:"failed"`
+}
+
+// BulkCreateJobs serves POST /jobs:bulk. The body is a JSON array of jobs,
+// or NDJSON (one job per line) when Content-Type is application/x-ndjson.
+// Items succeed or fail independently; the response lists one result per
+// item in request order.
+func (h *Handlers) BulkCreateJobs(w http.ResponseWriter, r *http.Request) {
+	var items []json.RawMessage
+	var err error
+	if strings.Contains(r.Header.Get("Content-Type"), "ndjson") {
+		items, err = readNDJSON(r.Body)
+	} else {
+		err = json.NewDecoder(r.Body).Decode(&items)
+	}
+	if err != nil {
+		writeError(w, r, badJSON(err))
+		return
+	}
+	if len(items) == 0 || len(items) > maxBulkItems {
+		writeError(w, r, invalid("invalid_bulk_size", fmt.Sprintf("send between 1 and %d jobs", maxBulkItems)))
+		return
+	}
+
+	caller := principalFrom(r.Context())
+	baseID := newID()
+	resp := bulkResp{Results: make([]bulkItemResp, len(items))}
+
+	// specs holds the valid items; index maps them back to their position.
+	specs := make([]domain.JobSpec, 0, len(items))
+	index := make([]int, 0, len(items))
+	for i, raw := range items {
+		resp.Results[i].Index = i
+
+		var req bulkJobReq
+		if err := json.Unmarshal(raw, &req); err != nil {
+			resp.Results[i].Error = itemError(r, badJSON(err))
+			continue
+		}
+		spec, err := h.jobSpec(r.Context(), req.createJobReq, caller)
+		if err != nil {
+			resp.Results[i].Error = itemError(r, err)
+			continue
+		}
+		spec.ID = fmt.Sprintf("%s-%d", baseID, i)
+		if req.IdempotencyKey != "" {
+			spec.IdempotencyKey = &req.IdempotencyKey
+		}
+		specs = append(specs, spec)
+		index = append(index, i)
+	}
+
+	for n, res := range h.Jobs.CreateMany(r.Context(), specs) {
+		item := &resp.Results[index[n]]
+		item.JobID = res.JobID
+		if res.Err != nil {
+			item.Error = itemError(r, createError(res.Err))
+			continue
+		}
+		item.Status = string(res.Outcome)
+	}
+
+	for i := range resp.Results {
+		switch {
+		case resp.Results[i].Error != nil:
+			resp.Results[i].Status = "error"
+			resp.Failed++
+		case resp.Results[i].Status == string(domain.OutcomeReplayed):
+			resp.Replayed++
+		default:
+			resp.Created++
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// readNDJSON splits body into one raw JSON value per non-blank line.
+func readNDJSON(body io.Reader) ([]json.RawMessage, error) {
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 0, 64*1024), 4<<20)
+
+	var items []json.RawMessage
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			return nil, fmt.Errorf("line %d: invalid json", len(items)+1)
+		}
+		items = append(items, append(json.RawMessage(nil), line...))
+		if len(items) > maxBulkItems {
+			break
+		}
+	}
+	return items, sc.Err()
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"task-scheduler/internal/domain"
+)
+
+// errorBody is the JSON body of every error response.
+type errorBody struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []fieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// fieldError points at one invalid field of the request.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// apiError is an error that knows its HTTP response. Handlers return or
+// write these for request-level problems; anything else that reaches
+// writeError is mapped from the domain errors or reported as a 500.
+type apiError struct {
+	status  int
+	code    string
+	message string
+	details []fieldError
+}
+
+func (e *apiError) Error() string { return e.code + ": " + e.message }
+
+func apiErr(status int, code, message string) *apiError {
+	return &apiError{status: status, code: code, message: message}
+}
+
+// invalid is a 400 carrying field-level details.
+func invalid(code, message string, details ...fieldError) *apiError {
+	return &apiError{status: http.StatusBadRequest, code: code, message: message, details: details}
+}
+
+func badJSON(err error) *apiError {
+	return apiErr(http.StatusBadRequest, "invalid_json", "request body is not valid JSON: "+err.Error())
+}
+
+var errForbidden = apiErr(http.StatusForbidden, "forbidden", "the API key does not allow this")
+
+// domainErrors maps domain sentinels to responses. Order matters: the
+// first match wins.
+var domainErrors = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{domain.ErrInvalidInput, http.StatusBadRequest, "invalid_input"},
+	{domain.ErrNotFound, http.StatusNotFound, "not_found"},
+	{domain.ErrQuotaExceeded, http.StatusTooManyRequests, "quota_exceeded"},
+	{domain.ErrDependencyFailed, http.StatusConflict, "dependency_failed"},
+	{domain.ErrConflict, http.StatusConflict, "conflict"},
+}
+
+// toAPIError maps err to the response it should produce. Unknown errors
+// become a 500 whose message does not leak internals.
+func toAPIError(err error) *apiError {
+	var ae *apiError
+	if errors.As(err, &ae) {
+		return ae
+	}
+	var ve *domain.ValidationError
+	if errors.As(err, &ve) {
+		return fromValidation(ve)
+	}
+	for _, m := range domainErrors {
+		if errors.Is(err, m.err) {
+			// "conflict: job already SUCCESS" -> "job already SUCCESS"
+			msg := strings.TrimPrefix(err.Error(), m.err.Error()+": ")
+			return apiErr(m.status, m.code, msg)
+		}
+	}
+	return apiErr(http.StatusInternalServerError, "internal_error", "internal error")
+}
+
+// fromValidation renders a business-rule violation from JobService. Payloads
+// over the size limit are 413, everything else 400.
+func fromValidation(ve *domain.ValidationError) *apiError {
+	ae := invalid(ve.Code, ve.Message)
+	if ve.Code == "payload_too_large" {
+		ae.status = http.StatusRequestEntityTooLarge
+	}
+	for _, f := range ve.Fields {
+		ae.details = append(ae.details, fieldError{f.Field, f.Message})
+	}
+	return ae
+}
+
+// atField prefixes the fields of a rule violation with the position of the
+// offending item, e.g. "type" -> "jobs[3].type". Other errors pass through.
+func atField(err error, prefix string) error {
+	var ve *domain.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+	out := *ve
+	out.Fields = make([]domain.FieldError, len(ve.Fields))
+	for i, f := range ve.Fields {
+		out.Fields[i] = domain.FieldError{Field: prefix + "." + f.Field, Message: f.Message}
+	}
+	return &out
+}
+
+// writeError writes err as a JSON error response. 5xx causes are logged
+// with the request ID so the response can be traced back to them.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	ae := toAPIError(err)
+	reqID := requestIDFrom(r.Context())
+	if ae.status >= 500 {
+		log.Printf("request %s: %s %s: %v", reqID, r.Method, r.URL.Path, err)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(ae.status)
+	_ = json.NewEncoder(w).Encode(errorBody{
+		Code:      ae.code,
+		Message:   ae.message,
+		Details:   ae.details,
+		RequestID: reqID,
+	})
+}
+
+// itemError renders err for one item of a multi-item response, where the
+// request as a whole still succeeds.
+func itemError(r *http.Request, err error) *errorBody {
+	ae := toAPIError(err)
+	if ae.status >= 500 {
+		log.Printf("request %s: %s %s: %v", requestIDFrom(r.Context()), r.Method, r.URL.Path, err)
+	}
+	return &errorBody{Code: ae.code, Message: ae.message, Details: ae.details}
+}
+
+// notFound replaces http.NotFound for unknown routes and methods.
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, apiErr(http.StatusNotFound, "not_found", "no such resource"))
+}
+
+// recode keeps err's status and message but reports it under code, for
+// endpoints whose clients rely on a more specific code than the default.
+func recode(err error, code string) *apiError {
+	ae := *toAPIError(err)
+	ae.code = code
+	return &ae
+}
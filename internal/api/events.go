@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+const (
+	eventPollInterval = 500 * time.Millisecond
+	eventKeepAlive    = 15 * time.Second
+	eventBatchSize    = 200
+	eventRetryMs      = 2000
+)
+
+// StreamEvents serves GET /events as Server-Sent Events.
+//
+// Events are read from the job_events table rather than from memory, so a
+// client sees changes made by any API instance or worker. Reconnecting
+// clients resume from the Last-Event-ID header (or ?last_event_id=);
+// new clients start at the tail of the log.
+func (h *Handlers) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, errors.New("response writer does not support flushing"))
+		return
+	}
+
+	q := r.URL.Query()
+	filter := domain.EventFilter{
+		TenantID: principalFrom(r.Context()).TenantID,
+		JobID:    strings.TrimSpace(q.Get("job_id")),
+		JobType:  strings.TrimSpace(q.Get("type")),
+		Queue:    strings.TrimSpace(q.Get("queue")),
+	}
+
+	cursor, ok := lastEventID(r)
+	if !ok {
+		writeError(w, r, invalid("invalid_last_event_id", "Last-Event-ID must be a non-negative integer",
+			fieldError{"last_event_id", "must be a non-negative integer"}))
+		return
+	}
+	if cursor < 0 {
+		latest, err := h.Events.LatestEventID(r.Context())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		cursor = latest
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventRetryMs)
+	flusher.Flush()
+
+	ctx := r.Context()
+	caller := principalFrom(ctx)
+	poll := time.NewTicker(eventPollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		events, err := h.Events.ListEvents(ctx, cursor, filter, eventBatchSize)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("events: list after=%d failed: %v", cursor, err)
+		}
+
+		for _, e := range events {
+			cursor = e.ID
+			if !caller.Allows(e.JobType, e.Queue) {
+				continue
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+		}
+		if len(events) == eventBatchSize {
+			// Still catching up; skip the poll delay.
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// lastEventID returns the resume cursor, or -1 when the client did not send one.
+func lastEventID(r *http.Request) (int64, bool) {
+	raw := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+	if raw == "" {
+		raw = strings.TrimSpace(r.URL.Query().Get("last_event_id"))
+	}
+	if raw == "" {
+		return -1, true
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+func writeSSE(w http.ResponseWriter, e domain.JobEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
@@ -1,26 +1,128 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"task-scheduler/internal/blob"
+	"task-scheduler/internal/codec"
+	"task-scheduler/internal/domain"
+	"task-scheduler/internal/keyring"
 	"task-scheduler/internal/repo"
+	"task-scheduler/internal/service"
 )
 
 type Handlers struct {
-	Repo repo.JobRepository
+	Jobs       *service.JobService
+	Events     repo.EventRepository
+	Webhooks   repo.WebhookRepository
+	APIKeys    repo.APIKeyRepository
+	Tenants    repo.TenantRepository
+	RateLimits repo.RateLimitRepository
+
+	ConcurrencyLimits repo.ConcurrencyLimitRepository
+	Workflows         repo.WorkflowRepository
+	Batches           repo.BatchRepository
+	Queues            repo.QueueRepository
+	Schemas           *service.SchemaRegistry
+	Retention         repo.RetentionRepository
+
+	// Keyring opens encrypted payloads for callers with jobs:payload.
+	Keyring *keyring.Keyring
+	// Blobs loads offloaded payloads for callers with jobs:payload.
+	Blobs blob.Store
 }
 
-func NewHandlers(r repo.JobRepository) *Handlers {
-	return &Handlers{Repo: r}
+func NewHandlers(d Deps) *Handlers {
+	return &Handlers{
+		Jobs:       d.Jobs,
+		Events:     d.Events,
+		Webhooks:   d.Webhooks,
+		APIKeys:    d.APIKeys,
+		Tenants:    d.Tenants,
+		RateLimits: d.RateLimits,
+
+		ConcurrencyLimits: d.ConcurrencyLimits,
+		Workflows:         d.Workflows,
+		Batches:           d.Batches,
+		Queues:            d.Queues,
+		Schemas:           d.Schemas,
+		Retention:         d.Retention,
+
+		Keyring: d.Keyring,
+		Blobs:   d.Blobs,
+	}
 }
 
 type createJobReq struct {
 	Type        string          `json:"type"`
+	Queue       string          `json:"queue"`
 	Payload     json.RawMessage `json:"payload"`
 	MaxAttempts int             `json:"max_attempts"`
+	CallbackURL string          `json:"callback_url"`
+
+	PayloadVersion int `json:"payload_version"`
+
+	Priority     int        `json:"priority"`
+	DelaySeconds int        `json:"delay_seconds"`
+	RunAt        *time.Time `json:"run_at"`
+
+	ConcurrencyKey string     `json:"concurrency_key"`
+	Unique         *uniqueReq `json:"unique"`
+
+	DependsOn       []string                   `json:"depends_on"`
+	OnParentFailure domain.ParentFailurePolicy `json:"on_parent_failure"`
+}
+
+// uniqueReq names the unique key either directly or by payload fields.
+type uniqueReq struct {
+	Key           string              `json:"key"`
+	Fields        []string            `json:"fields"`
+	Policy        domain.UniquePolicy `json:"policy"`
+	WindowSeconds int                 `json:"window_seconds"`
+}
+
+func (u *uniqueReq) spec(payload json.RawMessage) (*domain.UniqueSpec, error) {
+	if u == nil {
+		return nil, nil
+	}
+	key := strings.TrimSpace(u.Key)
+	switch {
+	case key != "" && len(u.Fields) > 0:
+		return nil, errors.New("unique: key and fields are mutually exclusive")
+	case key == "" && len(u.Fields) == 0:
+		return nil, errors.New("unique: key or fields required")
+	case len(key) > domain.MaxUniqueKeyLen:
+		return nil, errors.New("unique: key too long")
+	case len(u.Fields) > 0:
+		var err error
+		if key, err = domain.UniqueKeyFromFields(payload, u.Fields); err != nil {
+			return nil, err
+		}
+	}
+
+	switch u.Policy {
+	case "":
+		u.Policy = domain.UniqueReturnExisting
+	case domain.UniqueReturnExisting, domain.UniqueReplace:
+	default:
+		return nil, errors.New("unique: unknown policy")
+	}
+	if u.WindowSeconds < 0 {
+		return nil, errors.New("unique: window_seconds must be >= 0")
+	}
+
+	return &domain.UniqueSpec{
+		Key:    key,
+		Policy: u.Policy,
+		Window: time.Duration(u.WindowSeconds) * time.Second,
+	}, nil
 }
 
 func (h *Handlers) Healthz(w http.ResponseWriter, r *http.Request) {
@@ -31,56 +133,424 @@ func (h *Handlers) Healthz(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) CreateJob(w http.ResponseWriter, r *http.Request) {
 	var req createJobReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+		writeError(w, r, badJSON(err))
 		return
 	}
-	if strings.TrimSpace(req.Type) == "" || len(req.Payload) == 0 {
-		http.Error(w, `{"error":"type_and_payload_required"}`, http.StatusBadRequest)
+
+	spec, err := h.jobSpec(r.Context(), req, principalFrom(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	if req.MaxAttempts <= 0 {
-		req.MaxAttempts = 3
+	if idempotency := r.Header.Get("Idempotency-Key"); idempotency != "" {
+		spec.IdempotencyKey = &idempotency
 	}
 
-	idempotency := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
-	var idemPtr *string
-	if idempotency != "" {
-		idemPtr = &idempotency
+	job, outcome, err := h.Jobs.Create(r.Context(), spec)
+	if err != nil {
+		writeError(w, r, createError(err))
+		return
 	}
 
-	jobID := newID()
+	switch outcome {
+	case domain.OutcomeReplayed:
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(http.StatusOK)
+	case domain.OutcomeDeduplicated, domain.OutcomeReplaced:
+		w.Header().Set("Deduplicated", string(outcome))
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusCreated)
+	}
+	h.showPayload(r, job)
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// jobSpec builds the job to create from req on behalf of caller. Request
+// shape is checked here; the business rules are JobService.Prepare's.
+func (h *Handlers) jobSpec(ctx context.Context, req createJobReq, caller *domain.APIKey) (domain.JobSpec, error) {
+	runAt := req.RunAt
+	switch {
+	case req.DelaySeconds < 0:
+		return domain.JobSpec{}, invalid("invalid_delay", "delay_seconds must not be negative",
+			fieldError{"delay_seconds", "must be >= 0"})
+	case req.DelaySeconds > 0 && req.RunAt != nil:
+		return domain.JobSpec{}, invalid("invalid_delay", "delay_seconds and run_at are mutually exclusive",
+			fieldError{"run_at", "not allowed with delay_seconds"})
+	case req.DelaySeconds > 0:
+		t := time.Now().Add(time.Duration(req.DelaySeconds) * time.Second)
+		runAt = &t
+	}
 
-	job, err := h.Repo.CreateJob(r.Context(), jobID, req.Type, req.Payload, req.MaxAttempts, idemPtr)
+	unique, err := req.Unique.spec(req.Payload)
 	if err != nil {
-		http.Error(w, `{"error":"create_failed"}`, http.StatusInternalServerError)
-		return
+		return domain.JobSpec{}, invalid("invalid_unique", err.Error(), fieldError{"unique", err.Error()})
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(job)
+	spec := domain.JobSpec{
+		ID:              newID(),
+		TenantID:        caller.TenantID,
+		Type:            req.Type,
+		Queue:           req.Queue,
+		Payload:         req.Payload,
+		PayloadVersion:  req.PayloadVersion,
+		MaxAttempts:     req.MaxAttempts,
+		Priority:        req.Priority,
+		RunAt:           runAt,
+		CallbackURL:     &req.CallbackURL,
+		ConcurrencyKey:  &req.ConcurrencyKey,
+		Unique:          unique,
+		DependsOn:       req.DependsOn,
+		OnParentFailure: req.OnParentFailure,
+		APIKeyID:        &caller.ID,
+	}
+	if err := h.Jobs.Prepare(ctx, &spec); err != nil {
+		return domain.JobSpec{}, err
+	}
+	if !caller.Allows(spec.Type, spec.Queue) {
+		return domain.JobSpec{}, errForbidden
+	}
+	return spec, nil
+}
+
+// createError maps a JobService.Create error to its response, keeping the
+// codes POST /jobs has always used. Rule violations keep their own code.
+func createError(err error) error {
+	var ve *domain.ValidationError
+	switch {
+	case errors.As(err, &ve):
+		return err
+	case errors.Is(err, domain.ErrConflict):
+		return recode(err, "idempotency_conflict")
+	case errors.Is(err, domain.ErrInvalidInput):
+		return recode(err, "invalid_payload")
+	default:
+		return err
+	}
 }
 
 func (h *Handlers) GetJob(w http.ResponseWriter, r *http.Request) {
-	// Expect /jobs/{id}
-	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
-	if id == "" || strings.Contains(id, "/") {
-		http.NotFound(w, r)
+	id, _ := jobPath(r.URL.Path)
+	if id == "" {
+		notFound(w, r)
 		return
 	}
 
-	job, err := h.Repo.GetJobByID(r.Context(), id)
+	job, ok := h.visibleJob(w, r, id)
+	if !ok {
+		return
+	}
+
+	h.showPayload(r, job)
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// visibleJob loads id and hides it (404) from keys restricted to other
+// types or queues. It writes the error response itself when ok is false.
+func (h *Handlers) visibleJob(w http.ResponseWriter, r *http.Request, id string) (*domain.Job, bool) {
+	caller := principalFrom(r.Context())
+	job, err := h.Jobs.Get(r.Context(), caller.TenantID, id)
 	if err != nil {
-		http.Error(w, `{"error":"fetch_failed"}`, http.StatusInternalServerError)
+		writeError(w, r, err)
+		return nil, false
+	}
+	if job == nil || !caller.Allows(job.Type, job.Queue) {
+		writeError(w, r, apiErr(http.StatusNotFound, "not_found", "job not found"))
+		return nil, false
+	}
+	return job, true
+}
+
+// CancelJob serves POST /jobs/{id}/cancel.
+func (h *Handlers) CancelJob(w http.ResponseWriter, r *http.Request) {
+	id, _ := jobPath(r.URL.Path)
+	if id == "" {
+		notFound(w, r)
+		return
+	}
+	if _, ok := h.visibleJob(w, r, id); !ok {
 		return
 	}
+
+	job, err := h.Jobs.Cancel(r.Context(), principalFrom(r.Context()).TenantID, id)
+	switch {
+	case errors.Is(err, domain.ErrConflict):
+		writeError(w, r, recode(err, "not_cancellable"))
+		return
+	case err != nil:
+		writeError(w, r, err)
+		return
+	}
+
+	h.showPayload(r, job)
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+// showPayload prepares job.Payload for the caller: loaded from the blob
+// store, opened and decompressed if it holds jobs:payload, withheld
+// otherwise. Payloads that cannot be decoded here are withheld too.
+func (h *Handlers) showPayload(r *http.Request, job *domain.Job) {
 	if job == nil {
-		http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+		return
+	}
+	job.PayloadOffloaded = job.PayloadRef != nil
+	if principalFrom(r.Context()).HasScope(domain.ScopeJobsPayload) && h.loadPayload(r, job) && h.openPayload(r, job) {
+		p, err := codec.Decode(job.PayloadEncoding, job.Payload)
+		if err == nil {
+			job.Payload = p
+			return
+		}
+		log.Printf("request %s: job %s: %v", requestIDFrom(r.Context()), job.ID, err)
+	}
+	job.Payload, job.PayloadRedacted = nil, true
+}
+
+// openPayload decrypts a sealed payload. It reports false if it could not.
+func (h *Handlers) openPayload(r *http.Request, job *domain.Job) bool {
+	if !keyring.Sealed(job.Payload) {
+		return true
+	}
+	if h.Keyring == nil {
+		return false
+	}
+	p, err := h.Keyring.Open(job.Payload, job.ID)
+	if err != nil {
+		log.Printf("request %s: job %s: %v", requestIDFrom(r.Context()), job.ID, err)
+		return false
+	}
+	job.Payload = p
+	return true
+}
+
+// loadPayload replaces an offloaded job's payload with the blob it points
+// at. It reports false if the blob could not be loaded.
+func (h *Handlers) loadPayload(r *http.Request, job *domain.Job) bool {
+	if job.PayloadRef == nil {
+		return true
+	}
+	if h.Blobs == nil {
+		log.Printf("request %s: job %s: payload is offloaded but no blob store is configured", requestIDFrom(r.Context()), job.ID)
+		return false
+	}
+	p, err := h.Blobs.Get(r.Context(), *job.PayloadRef)
+	if err != nil {
+		log.Printf("request %s: job %s: %v", requestIDFrom(r.Context()), job.ID, err)
+		return false
+	}
+	job.Payload = p
+	return true
+}
+
+// ListJobs serves GET /jobs?status=&type=&queue=&limit=&cursor=. Jobs are
+// listed newest first; next_cursor, when present, fetches the next page.
+func (h *Handlers) ListJobs(w http.ResponseWriter, r *http.Request) {
+	caller := principalFrom(r.Context())
+	q := r.URL.Query()
+	filter := domain.JobFilter{
+		TenantID: caller.TenantID,
+		Status:   domain.JobStatus(strings.ToUpper(strings.TrimSpace(q.Get("status")))),
+		Type:     strings.TrimSpace(q.Get("type")),
+		Queue:    strings.TrimSpace(q.Get("queue")),
+		Before:   strings.TrimSpace(q.Get("cursor")),
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	jobs, err := h.Jobs.List(r.Context(), filter, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// The cursor follows the rows read, not the rows shown, so keys
+	// restricted to some types or queues can still page past the rest.
+	resp := struct {
+		Jobs       []domain.Job `json:"jobs"`
+		NextCursor string       `json:"next_cursor,omitempty"`
+	}{Jobs: []domain.Job{}}
+	for _, j := range jobs {
+		if caller.Allows(j.Type, j.Queue) {
+			if j.PayloadRef != nil {
+				// Offloaded payloads are only loaded by GET /jobs/{id}.
+				j.Payload, j.PayloadOffloaded = nil, true
+			} else {
+				h.showPayload(r, &j)
+			}
+			resp.Jobs = append(resp.Jobs, j)
+		}
+	}
+	if len(jobs) == limit {
+		resp.NextCursor = jobs[len(jobs)-1].ID
+	}
+
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// PurgeJob serves DELETE /jobs/{id}: a finished job is deleted with its
+// events, steps and offloaded payload.
+func (h *Handlers) PurgeJob(w http.ResponseWriter, r *http.Request) {
+	id, _ := jobPath(r.URL.Path)
+	if id == "" {
+		notFound(w, r)
+		return
+	}
+	if _, ok := h.visibleJob(w, r, id); !ok {
+		return
+	}
+
+	err := h.Jobs.Purge(r.Context(), principalFrom(r.Context()).TenantID, id)
+	switch {
+	case errors.Is(err, domain.ErrConflict):
+		writeError(w, r, recode(err, "not_finished"))
+		return
+	case err != nil:
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RetryJob serves POST /jobs/{id}/retry.
+func (h *Handlers) RetryJob(w http.ResponseWriter, r *http.Request) {
+	id, _ := jobPath(r.URL.Path)
+	if id == "" {
+		notFound(w, r)
+		return
+	}
+	if _, ok := h.visibleJob(w, r, id); !ok {
+		return
+	}
+
+	job, err := h.Jobs.Retry(r.Context(), principalFrom(r.Context()).TenantID, id)
+	switch {
+	case errors.Is(err, domain.ErrConflict):
+		writeError(w, r, recode(err, "not_retryable"))
+		return
+	case err != nil:
+		writeError(w, r, err)
 		return
 	}
 
+	h.showPayload(r, job)
 	_ = json.NewEncoder(w).Encode(job)
 }
 
+type retryJobsReq struct {
+	Status domain.JobStatus `json:"status"`
+	Type   string           `json:"type"`
+	Queue  string           `json:"queue"`
+	Limit  int              `json:"limit"`
+}
+
+// RetryJobs serves POST /jobs:retry, requeueing up to limit FAILED (or
+// CANCELLED) jobs matching type and queue, newest first. Jobs that cannot be
+// retried are skipped.
+func (h *Handlers) RetryJobs(w http.ResponseWriter, r *http.Request) {
+	var req retryJobsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badJSON(err))
+		return
+	}
+
+	caller := principalFrom(r.Context())
+	filter := domain.JobFilter{
+		TenantID: caller.TenantID,
+		Status:   req.Status,
+		Type:     strings.TrimSpace(req.Type),
+		Queue:    strings.TrimSpace(req.Queue),
+	}
+	res, err := h.Jobs.RetryMatching(r.Context(), filter, req.Limit, func(j domain.Job) bool {
+		return caller.Allows(j.Type, j.Queue)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+// PayloadVersions serves GET /admin/payload-versions: queued jobs per type
+// and payload version. Operators (tenants:admin) see every tenant unless
+// ?tenant_id= is given.
+func (h *Handlers) PayloadVersions(w http.ResponseWriter, r *http.Request) {
+	counts, err := h.Jobs.PayloadVersions(r.Context(), scopedTenant(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if counts == nil {
+		counts = []domain.PayloadVersionCount{}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"versions": counts})
+}
+
+// ListJobDeliveries serves GET /jobs/{id}/deliveries.
+func (h *Handlers) ListJobDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, _ := jobPath(r.URL.Path)
+	if id == "" {
+		notFound(w, r)
+		return
+	}
+	if _, ok := h.visibleJob(w, r, id); !ok {
+		return
+	}
+	h.listDeliveries(w, r, domain.DeliveryFilter{
+		TenantID: principalFrom(r.Context()).TenantID,
+		JobID:    id,
+	})
+}
+
+// ListDeliveries serves GET /webhooks/deliveries?status=FAILED&job_id=...
+// Operators (tenants:admin) see every tenant unless ?tenant_id= is given.
+func (h *Handlers) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := domain.DeliveryFilter{
+		TenantID: scopedTenant(r),
+		JobID:    strings.TrimSpace(q.Get("job_id")),
+		Status:   domain.DeliveryStatus(strings.ToUpper(strings.TrimSpace(q.Get("status")))),
+	}
+	switch filter.Status {
+	case "", domain.DeliveryPending, domain.DeliveryDelivered, domain.DeliveryFailed:
+	default:
+		writeError(w, r, invalid("invalid_status", "unknown delivery status",
+			fieldError{"status", "must be PENDING, DELIVERED or FAILED"}))
+		return
+	}
+	h.listDeliveries(w, r, filter)
+}
+
+func (h *Handlers) listDeliveries(w http.ResponseWriter, r *http.Request, filter domain.DeliveryFilter) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	deliveries, err := h.Webhooks.ListDeliveries(r.Context(), filter, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []domain.WebhookDelivery{}
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]any{"deliveries": deliveries})
+}
+
+// jobPath splits /jobs/{id}[/{action}] into its parts.
+func jobPath(path string) (id, action string) {
+	rest := strings.TrimPrefix(path, "/jobs/")
+	id, action, _ = strings.Cut(rest, "/")
+	if strings.Contains(action, "/") {
+		return "", ""
+	}
+	return id, action
+}
+
 // tiny ID generator (replace with uuid if you already use one)
 func newID() string {
 	return strings.ReplaceAll(time.Now().UTC().Format("20060102150405.000000000"), ".", "")
@@ -3,39 +3,320 @@ package api
 import (
 	"net/http"
 
+	"task-scheduler/internal/blob"
+	"task-scheduler/internal/domain"
+	"task-scheduler/internal/keyring"
 	"task-scheduler/internal/repo"
+	"task-scheduler/internal/service"
 )
 
 type Server struct {
 	h http.Handler
 }
 
-func NewServer(r repo.JobRepository) *Server {
-	handlers := NewHandlers(r)
+// Deps wires the HTTP API to storage and request-level settings.
+type Deps struct {
+	Jobs       *service.JobService
+	Events     repo.EventRepository
+	Webhooks   repo.WebhookRepository
+	APIKeys    repo.APIKeyRepository
+	Tenants    repo.TenantRepository
+	RateLimits repo.RateLimitRepository
+
+	ConcurrencyLimits repo.ConcurrencyLimitRepository
+	Workflows         repo.WorkflowRepository
+	Batches           repo.BatchRepository
+	Queues            repo.QueueRepository
+	Schemas           *service.SchemaRegistry
+	Retention         repo.RetentionRepository
+
+	// Keyring opens encrypted payloads for callers with jobs:payload; nil if
+	// payloads are not encrypted.
+	Keyring *keyring.Keyring
+	// Blobs holds offloaded payloads; nil if payloads are not offloaded.
+	Blobs blob.Store
+	// BootstrapKey is accepted as a jobs:admin key; used to mint the first keys.
+	BootstrapKey string
+}
+
+func NewServer(d Deps) *Server {
+	handlers := NewHandlers(d)
+
+	var (
+		canCreate = func(h http.HandlerFunc) http.HandlerFunc { return requireScope(domain.ScopeJobsCreate, h) }
+		canRead   = func(h http.HandlerFunc) http.HandlerFunc { return requireScope(domain.ScopeJobsRead, h) }
+		isAdmin   = func(h http.HandlerFunc) http.HandlerFunc { return requireScope(domain.ScopeJobsAdmin, h) }
+		operator  = func(h http.HandlerFunc) http.HandlerFunc { return requireScope(domain.ScopeTenantsAdmin, h) }
+	)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", handlers.Healthz)
 
 	// Routes:
-	// POST /jobs
-	// GET  /jobs/{id}
+	// POST   /jobs                            jobs:create
+	// GET    /jobs                            jobs:read
+	// POST   /jobs:bulk                       jobs:create
+	// POST   /jobs:retry                      jobs:admin
+	// GET    /jobs/{id}                       jobs:read
+	// DELETE /jobs/{id}                       jobs:admin
+	// POST   /jobs/{id}/cancel                jobs:admin
+	// POST   /jobs/{id}/retry                 jobs:admin
+	// GET    /jobs/{id}/deliveries            jobs:read
+	// GET    /queues                          jobs:read
+	// POST   /queues/{queue}/pause            jobs:admin
+	// POST   /queues/{queue}/resume           jobs:admin
+	// POST   /workflows                       jobs:create
+	// GET    /workflows/{id}                  jobs:read
+	// POST   /batches                         jobs:create
+	// GET    /batches/{id}                    jobs:read
+	// GET    /events                          jobs:read
+	// GET    /webhooks/deliveries             jobs:admin
+	// POST   /admin/api-keys                  jobs:admin
+	// GET    /admin/api-keys                  jobs:admin
+	// DELETE /admin/api-keys/{id}             jobs:admin
+	// GET    /admin/payload-versions          jobs:admin
+	// GET    /admin/tenants                   tenants:admin
+	// GET    /admin/tenants/{id}/quota        tenants:admin
+	// PUT    /admin/tenants/{id}/quota        tenants:admin
+	// GET    /admin/rate-limits               tenants:admin
+	// PUT    /admin/rate-limits/{type}        tenants:admin
+	// DELETE /admin/rate-limits/{type}        tenants:admin
+	// GET    /admin/concurrency-limits        tenants:admin
+	// PUT    /admin/concurrency-limits/{type} tenants:admin
+	// DELETE /admin/concurrency-limits/{type} tenants:admin
+	// GET    /admin/job-schemas               tenants:admin
+	// PUT    /admin/job-schemas/{type}        tenants:admin
+	// DELETE /admin/job-schemas/{type}        tenants:admin
+	// GET    /admin/retention                 tenants:admin
+	//
+	// Everything except the /admin/tenants, /admin/rate-limits,
+	// /admin/concurrency-limits, /admin/job-schemas and /admin/retention
+	// routes is scoped to the caller's tenant.
 	mux.HandleFunc("/jobs", func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPost:
+			canCreate(handlers.CreateJob)(w, req)
+		case http.MethodGet:
+			canRead(handlers.ListJobs)(w, req)
+		default:
+			notFound(w, req)
+		}
+	})
+
+	mux.HandleFunc("/jobs:bulk", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost {
+			canCreate(handlers.BulkCreateJobs)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/jobs:retry", func(w http.ResponseWriter, req *http.Request) {
 		if req.Method == http.MethodPost {
-			handlers.CreateJob(w, req)
+			isAdmin(handlers.RetryJobs)(w, req)
 			return
 		}
-		http.NotFound(w, req)
+		notFound(w, req)
 	})
 
 	mux.HandleFunc("/jobs/", func(w http.ResponseWriter, req *http.Request) {
+		_, action := jobPath(req.URL.Path)
+		switch {
+		case req.Method == http.MethodGet && action == "":
+			canRead(handlers.GetJob)(w, req)
+		case req.Method == http.MethodDelete && action == "":
+			isAdmin(handlers.PurgeJob)(w, req)
+		case req.Method == http.MethodPost && action == "cancel":
+			isAdmin(handlers.CancelJob)(w, req)
+		case req.Method == http.MethodPost && action == "retry":
+			isAdmin(handlers.RetryJob)(w, req)
+		case req.Method == http.MethodGet && action == "deliveries":
+			canRead(handlers.ListJobDeliveries)(w, req)
+		default:
+			notFound(w, req)
+		}
+	})
+
+	mux.HandleFunc("/queues", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			canRead(handlers.ListQueues)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/queues/", func(w http.ResponseWriter, req *http.Request) {
+		_, action := queuePath(req.URL.Path)
+		switch {
+		case req.Method == http.MethodPost && action == "pause":
+			isAdmin(handlers.PauseQueue)(w, req)
+		case req.Method == http.MethodPost && action == "resume":
+			isAdmin(handlers.ResumeQueue)(w, req)
+		default:
+			notFound(w, req)
+		}
+	})
+
+	mux.HandleFunc("/workflows", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost {
+			canCreate(handlers.CreateWorkflow)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/workflows/", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			canRead(handlers.GetWorkflow)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/batches", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost {
+			canCreate(handlers.CreateBatch)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/batches/", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			canRead(handlers.GetBatch)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/events", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			canRead(handlers.StreamEvents)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/webhooks/deliveries", func(w http.ResponseWriter, req *http.Request) {
 		if req.Method == http.MethodGet {
-			handlers.GetJob(w, req)
+			isAdmin(handlers.ListDeliveries)(w, req)
 			return
 		}
-		http.NotFound(w, req)
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/admin/api-keys", func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPost:
+			isAdmin(handlers.CreateAPIKey)(w, req)
+		case http.MethodGet:
+			isAdmin(handlers.ListAPIKeys)(w, req)
+		default:
+			notFound(w, req)
+		}
+	})
+
+	mux.HandleFunc("/admin/api-keys/", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodDelete {
+			isAdmin(handlers.RevokeAPIKey)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/admin/payload-versions", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			isAdmin(handlers.PayloadVersions)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/admin/retention", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			operator(handlers.RetentionStatus)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/admin/tenants", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			operator(handlers.ListQuotas)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/admin/tenants/", func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			operator(handlers.GetQuota)(w, req)
+		case http.MethodPut:
+			operator(handlers.SetQuota)(w, req)
+		default:
+			notFound(w, req)
+		}
+	})
+
+	mux.HandleFunc("/admin/rate-limits", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			operator(handlers.ListRateLimits)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/admin/rate-limits/", func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPut:
+			operator(handlers.SetRateLimit)(w, req)
+		case http.MethodDelete:
+			operator(handlers.DeleteRateLimit)(w, req)
+		default:
+			notFound(w, req)
+		}
+	})
+
+	mux.HandleFunc("/admin/concurrency-limits", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			operator(handlers.ListConcurrencyLimits)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/admin/concurrency-limits/", func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPut:
+			operator(handlers.SetConcurrencyLimit)(w, req)
+		case http.MethodDelete:
+			operator(handlers.DeleteConcurrencyLimit)(w, req)
+		default:
+			notFound(w, req)
+		}
+	})
+
+	mux.HandleFunc("/admin/job-schemas", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			operator(handlers.ListSchemas)(w, req)
+			return
+		}
+		notFound(w, req)
+	})
+
+	mux.HandleFunc("/admin/job-schemas/", func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodPut:
+			operator(handlers.SetSchema)(w, req)
+		case http.MethodDelete:
+			operator(handlers.DeleteSchema)(w, req)
+		default:
+			notFound(w, req)
+		}
 	})
 
-	return &Server{h: withMiddleware(mux)}
+	return &Server{h: withMiddleware(authMW(d.APIKeys, d.BootstrapKey, mux))}
 }
 
 func (s *Server) Handler() http.Handler {
@@ -1,13 +1,41 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"log"
 	"net/http"
+	"runtime/debug"
 	"time"
 )
 
 func withMiddleware(h http.Handler) http.Handler {
-	return recoverMW(loggingMW(jsonMW(h)))
+	return requestIDMW(recoverMW(loggingMW(jsonMW(h))))
+}
+
+type requestIDKey struct{}
+
+// requestIDFrom returns the ID assigned by requestIDMW, or "".
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDMW tags each request with an ID, taken from a sane incoming
+// X-Request-ID or generated, and echoes it in the response.
+func requestIDMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 128 {
+			var b [8]byte
+			_, _ = rand.Read(b[:])
+			id = hex.EncodeToString(b[:])
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
 }
 
 func jsonMW(next http.Handler) http.Handler {
@@ -24,7 +52,7 @@ func loggingMW(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
+		log.Printf("%s %s %s request_id=%s", r.Method, r.URL.Path, time.Since(start), requestIDFrom(r.Context()))
 	})
 }
 
@@ -32,7 +60,10 @@ func recoverMW(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if rec := recover(); rec != nil {
-				http.Error(w, `{"error":"internal_server_error"}`, http.StatusInternalServerError)
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				writeError(w, r, fmt.Errorf("panic: %v\n%s", rec, debug.Stack()))
 			}
 		}()
 		next.ServeHTTP(w, r)
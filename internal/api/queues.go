@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"task-scheduler/internal/domain"
+)
+
+// ListQueues serves GET /queues: job counts by status and pause state for
+// each of the caller's queues.
+func (h *Handlers) ListQueues(w http.ResponseWriter, r *http.Request) {
+	caller := principalFrom(r.Context())
+	queues, err := h.Queues.ListQueues(r.Context(), caller.TenantID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	visible := []domain.QueueStats{}
+	for _, q := range queues {
+		if allowedQueue(caller, q.Queue) {
+			visible = append(visible, q)
+		}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"queues": visible})
+}
+
+// PauseQueue serves POST /queues/{queue}/pause. Jobs can still be created
+// in a paused queue; workers just do not claim them.
+func (h *Handlers) PauseQueue(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, true)
+}
+
+// ResumeQueue serves POST /queues/{queue}/resume.
+func (h *Handlers) ResumeQueue(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, false)
+}
+
+func (h *Handlers) setPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	queue, _ := queuePath(r.URL.Path)
+	caller := principalFrom(r.Context())
+	if queue == "" || len(queue) > 64 {
+		writeError(w, r, invalid("invalid_queue", "queue name must be 1-64 characters"))
+		return
+	}
+	if !allowedQueue(caller, queue) {
+		writeError(w, r, errForbidden)
+		return
+	}
+
+	var err error
+	if paused {
+		err = h.Queues.PauseQueue(r.Context(), caller.TenantID, queue)
+	} else {
+		err = h.Queues.ResumeQueue(r.Context(), caller.TenantID, queue)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"queue": queue, "paused": paused})
+}
+
+// allowedQueue reports whether caller's key is not restricted away from queue.
+func allowedQueue(caller *domain.APIKey, queue string) bool {
+	if len(caller.AllowedQueues) == 0 {
+		return true
+	}
+	for _, q := range caller.AllowedQueues {
+		if q == queue {
+			return true
+		}
+	}
+	return false
+}
+
+// queuePath splits "/queues/{queue}/{action}".
+func queuePath(path string) (queue, action string) {
+	rest := strings.TrimPrefix(path, "/queues/")
+	queue, action, _ = strings.Cut(rest, "/")
+	if strings.Contains(action, "/") {
+		return "", ""
+	}
+	return queue, action
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"task-scheduler/internal/domain"
+)
+
+type setSchemaReq struct {
+	Schema json.RawMessage `json:"schema"`
+}
+
+// ListSchemas serves GET /admin/job-schemas, including schemas from the
+// config file that the API has not overridden.
+func (h *Handlers) ListSchemas(w http.ResponseWriter, r *http.Request) {
+	schemas, err := h.Schemas.List(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"schemas": schemas})
+}
+
+// SetSchema serves PUT /admin/job-schemas/{type}. Jobs already queued are
+// checked against the new schema when a worker picks them up.
+func (h *Handlers) SetSchema(w http.ResponseWriter, r *http.Request) {
+	jobType := strings.TrimPrefix(r.URL.Path, "/admin/job-schemas/")
+	if jobType == "" || strings.Contains(jobType, "/") {
+		notFound(w, r)
+		return
+	}
+
+	var req setSchemaReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badJSON(err))
+		return
+	}
+	if len(req.Schema) == 0 {
+		writeError(w, r, invalid("invalid_schema", "schema is required", fieldError{"schema", "required"}))
+		return
+	}
+
+	if err := h.Schemas.Set(r.Context(), jobType, req.Schema); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(domain.PayloadSchema{JobType: jobType, Schema: req.Schema, Source: domain.SchemaSourceAPI})
+}
+
+// DeleteSchema serves DELETE /admin/job-schemas/{type}.
+func (h *Handlers) DeleteSchema(w http.ResponseWriter, r *http.Request) {
+	jobType := strings.TrimPrefix(r.URL.Path, "/admin/job-schemas/")
+	if jobType == "" || strings.Contains(jobType, "/") {
+		notFound(w, r)
+		return
+	}
+
+	if err := h.Schemas.Delete(r.Context(), jobType); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"task-scheduler/internal/domain"
+)
+
+// maxWorkflowNodes bounds a single workflow submission.
+const maxWorkflowNodes = 1000
+
+type createWorkflowReq struct {
+	Name  string            `json:"name"`
+	Nodes []workflowNodeReq `json:"nodes"`
+}
+
+type workflowNodeReq struct {
+	Key             string                     `json:"key"`
+	Type            string                     `json:"type"`
+	Queue           string                     `json:"queue"`
+	Payload         json.RawMessage            `json:"payload"`
+	MaxAttempts     int                        `json:"max_attempts"`
+	PayloadVersion  int                        `json:"payload_version"`
+	DependsOn       []string                   `json:"depends_on"`
+	OnParentFailure domain.ParentFailurePolicy `json:"on_parent_failure"`
+}
+
+// CreateWorkflow serves POST /workflows. Nodes reference each other by key;
+// the graph must be acyclic.
+func (h *Handlers) CreateWorkflow(w http.ResponseWriter, r *http.Request) {
+	var req createWorkflowReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, badJSON(err))
+		return
+	}
+
+	if len(req.Nodes) == 0 || len(req.Nodes) > maxWorkflowNodes {
+		msg := fmt.Sprintf("workflow needs 1 to %d nodes", maxWorkflowNodes)
+		writeError(w, r, invalid("invalid_workflow", msg, fieldError{"nodes", msg}))
+		return
+	}
+
+	// Nodes go through the same rules as single jobs, with depends_on still
+	// naming node keys; IDs are assigned once the graph is ordered.
+	caller := principalFrom(r.Context())
+	specs := make([]domain.JobSpec, len(req.Nodes))
+	for i, n := range req.Nodes {
+		key := strings.TrimSpace(n.Key)
+		specs[i] = domain.JobSpec{
+			Type:            n.Type,
+			Queue:           n.Queue,
+			Payload:         n.Payload,
+			PayloadVersion:  n.PayloadVersion,
+			MaxAttempts:     n.MaxAttempts,
+			APIKeyID:        &caller.ID,
+			DependsOn:       n.DependsOn,
+			OnParentFailure: n.OnParentFailure,
+			WorkflowNode:    &key,
+		}
+		if err := h.Jobs.Prepare(r.Context(), &specs[i]); err != nil {
+			writeError(w, r, atField(err, fmt.Sprintf("nodes[%s]", key)))
+			return
+		}
+		if !caller.Allows(specs[i].Type, specs[i].Queue) {
+			writeError(w, r, errForbidden)
+			return
+		}
+	}
+
+	specs, err := orderNodes(specs)
+	if err != nil {
+		writeError(w, r, invalid("invalid_workflow", err.Error(), fieldError{"nodes", err.Error()}))
+		return
+	}
+
+	wfID := newID()
+	ids := make(map[string]string, len(specs))
+	for i := range specs {
+		ids[*specs[i].WorkflowNode] = fmt.Sprintf("%s-%d", wfID, i)
+		specs[i].ID = ids[*specs[i].WorkflowNode]
+		parents := make([]string, len(specs[i].DependsOn))
+		for j, key := range specs[i].DependsOn {
+			parents[j] = ids[key]
+		}
+		specs[i].DependsOn = parents
+	}
+
+	wf, err := h.Workflows.CreateWorkflow(r.Context(), domain.WorkflowSpec{
+		ID:       wfID,
+		TenantID: caller.TenantID,
+		Name:     strings.TrimSpace(req.Name),
+		APIKeyID: &caller.ID,
+		Nodes:    specs,
+	})
+	switch {
+	case errors.Is(err, domain.ErrInvalidInput):
+		writeError(w, r, recode(err, "invalid_workflow"))
+		return
+	case err != nil:
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(wf)
+}
+
+// GetWorkflow serves GET /workflows/{id}.
+func (h *Handlers) GetWorkflow(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/workflows/")
+	if id == "" || strings.Contains(id, "/") {
+		notFound(w, r)
+		return
+	}
+
+	wf, err := h.Workflows.GetWorkflow(r.Context(), principalFrom(r.Context()).TenantID, id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if wf == nil || !h.canSeeWorkflow(r, wf) {
+		writeError(w, r, apiErr(http.StatusNotFound, "not_found", "workflow not found"))
+		return
+	}
+	_ = json.NewEncoder(w).Encode(wf)
+}
+
+// canSeeWorkflow hides workflows from keys restricted away from any of
+// their nodes, like visibleJob does for single jobs.
+func (h *Handlers) canSeeWorkflow(r *http.Request, wf *domain.Workflow) bool {
+	caller := principalFrom(r.Context())
+	for _, n := range wf.Nodes {
+		if !caller.Allows(n.Type, n.Queue) {
+			return false
+		}
+	}
+	return true
+}
+
+// orderNodes checks the node keys and the graph of prepared node specs,
+// whose DependsOn name node keys, and returns the specs in topological order,
+// keeping the submitted order among nodes that are ready at the same time.
+func orderNodes(nodes []domain.JobSpec) ([]domain.JobSpec, error) {
+	byKey := make(map[string]int, len(nodes))
+	for i, n := range nodes {
+		key := *n.WorkflowNode
+		if key == "" || len(key) > 100 {
+			return nil, fmt.Errorf("node %d: key must be 1 to 100 characters", i)
+		}
+		if _, dup := byKey[key]; dup {
+			return nil, fmt.Errorf("duplicate node key %s", key)
+		}
+		byKey[key] = i
+	}
+
+	indegree := make([]int, len(nodes))
+	children := make([][]int, len(nodes))
+	for i, n := range nodes {
+		for _, p := range n.DependsOn {
+			j, ok := byKey[p]
+			if !ok {
+				return nil, fmt.Errorf("node %s: unknown parent %s", *n.WorkflowNode, p)
+			}
+			children[j] = append(children[j], i)
+			indegree[i]++
+		}
+	}
+
+	var ready []int
+	for i, d := range indegree {
+		if d == 0 {
+			ready = append(ready, i)
+		}
+	}
+	ordered := make([]domain.JobSpec, 0, len(nodes))
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, nodes[i])
+		for _, c := range children[i] {
+			if indegree[c]--; indegree[c] == 0 {
+				ready = append(ready, c)
+			}
+		}
+	}
+	if len(ordered) != len(nodes) {
+		return nil, errors.New("workflow has a cycle")
+	}
+	return ordered, nil
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// KeyPrefix marks scheduler API keys so they are easy to spot in logs and
+// secret scanners.
+const KeyPrefix = "tsk_"
+
+// GenerateKey returns a new plaintext API key, the short prefix shown in
+// listings, and the hash that is stored. The plaintext is never persisted.
+func GenerateKey() (plain, prefix, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", fmt.Errorf("generate key: %w", err)
+	}
+	plain = KeyPrefix + hex.EncodeToString(b)
+	return plain, plain[:len(KeyPrefix)+8], HashKey(plain), nil
+}
+
+// HashKey returns the hex SHA-256 of a plaintext key. API keys carry 256 bits
+// of entropy, so a fast unsalted hash is sufficient for lookup.
+func HashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,60 @@
+// Package blob stores large job payloads outside MySQL. Rows keep only the
+// key of their blob; see mysqlrepo.PayloadOptions.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when no blob has the key.
+var ErrNotFound = errors.New("blob: not found")
+
+// Store is a flat key/value store for blobs. Keys are slash-separated paths
+// such as "jobs/<id>/<random>". Implementations must be safe for concurrent
+// use.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns ErrNotFound if key does not exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes key; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config selects and configures a Store.
+type Config struct {
+	// Kind is "fs", "s3", or "" for no store.
+	Kind string
+	// Dir is the root directory of the "fs" store.
+	Dir string
+	S3  S3Config
+}
+
+// Open returns the store described by c, or nil if c.Kind is empty.
+func Open(c Config) (Store, error) {
+	switch c.Kind {
+	case "":
+		return nil, nil
+	case "fs":
+		return NewFS(c.Dir)
+	case "s3":
+		return NewS3(c.S3)
+	default:
+		return nil, fmt.Errorf("blob: unknown store %q (want fs or s3)", c.Kind)
+	}
+}
+
+// checkKey rejects keys that could escape the store's root.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return fmt.Errorf("blob: invalid key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("blob: invalid key %q", key)
+		}
+	}
+	return nil
+}
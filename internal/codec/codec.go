@@ -0,0 +1,78 @@
+// Package codec compresses JSON documents (job payloads, step outputs) for
+// storage in JSON columns.
+//
+// An encoded document is still valid JSON: a string holding the base64 of
+// the gzip-compressed original. The encoding is recorded next to it (for
+// example jobs.payload_encoding) so rows written without compression keep
+// decoding as they are.
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Encodings.
+const (
+	Identity = "identity"
+	Gzip     = "gzip"
+)
+
+// Encode compresses doc if it is at least minBytes long and compression
+// makes it smaller. It returns the document to store and its encoding.
+// minBytes <= 0 disables compression.
+func Encode(doc json.RawMessage, minBytes int) (json.RawMessage, string, error) {
+	if minBytes <= 0 || len(doc) < minBytes {
+		return doc, Identity, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(doc); err != nil {
+		return nil, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, "", err
+	}
+	out, err := json.Marshal(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(out) >= len(doc) {
+		return doc, Identity, nil
+	}
+	return out, Gzip, nil
+}
+
+// Decode returns the original document of stored. An empty encoding is
+// treated as Identity.
+func Decode(encoding string, stored json.RawMessage) (json.RawMessage, error) {
+	switch encoding {
+	case "", Identity:
+		return stored, nil
+	case Gzip:
+		var s string
+		if err := json.Unmarshal(stored, &s); err != nil {
+			return nil, fmt.Errorf("codec: gzip document is not a JSON string: %w", err)
+		}
+		raw, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("codec: %w", err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("codec: %w", err)
+		}
+		defer zr.Close()
+		out, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("codec: %w", err)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("codec: unknown encoding %q", encoding)
+	}
+}
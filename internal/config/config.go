@@ -3,7 +3,10 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"task-scheduler/internal/blob"
 )
 
 type Config struct {
@@ -11,7 +14,27 @@ type Config struct {
 	DBDSN string
 
 	// api
-	Port string
+	Port           string
+	IdempotencyTTL time.Duration
+	AdminAPIKey    string
+
+	// job rules
+	MaxPayloadBytes  int
+	AllowedJobTypes  []string
+	MaxAttemptsLimit int
+	SchemasFile      string
+	SchemaCacheTTL   time.Duration
+
+	// PayloadKeyringFile enables payload encryption at rest when set.
+	PayloadKeyringFile string
+
+	// Payloads and step outputs of at least CompressMinBytes are stored
+	// gzip-compressed; 0 turns compression off.
+	CompressMinBytes int
+
+	// Payloads over BlobThresholdBytes go to Blob when it is configured.
+	Blob               blob.Config
+	BlobThresholdBytes int
 
 	// worker
 	WorkerID     string
@@ -22,8 +45,35 @@ type Config struct {
 
 func Load() Config {
 	return Config{
-		DBDSN:        envOr("DB_DSN", ""),
-		Port:         envOr("PORT", "8080"),
+		DBDSN:          envOr("DB_DSN", ""),
+		Port:           envOr("PORT", "8080"),
+		IdempotencyTTL: time.Duration(envInt("IDEMPOTENCY_TTL_SECONDS", 86400)) * time.Second,
+		AdminAPIKey:    envOr("ADMIN_API_KEY", ""),
+
+		MaxPayloadBytes:  envInt("MAX_PAYLOAD_BYTES", 1<<20),
+		AllowedJobTypes:  envList("ALLOWED_JOB_TYPES"),
+		MaxAttemptsLimit: envInt("MAX_ATTEMPTS_LIMIT", 25),
+		SchemasFile:      envOr("JOB_SCHEMAS_FILE", ""),
+		SchemaCacheTTL:   time.Duration(envInt("SCHEMA_CACHE_SECONDS", 30)) * time.Second,
+
+		PayloadKeyringFile: envOr("PAYLOAD_KEYRING_FILE", ""),
+
+		CompressMinBytes: envInt("COMPRESS_MIN_BYTES", 0),
+
+		Blob: blob.Config{
+			Kind: envOr("BLOB_STORE", ""),
+			Dir:  envOr("BLOB_DIR", "/var/lib/scheduler/blobs"),
+			S3: blob.S3Config{
+				Endpoint:        envOr("S3_ENDPOINT", ""),
+				Bucket:          envOr("S3_BUCKET", ""),
+				Region:          envOr("S3_REGION", "us-east-1"),
+				AccessKeyID:     envOr("S3_ACCESS_KEY_ID", ""),
+				SecretAccessKey: envOr("S3_SECRET_ACCESS_KEY", ""),
+				Prefix:          envOr("S3_PREFIX", ""),
+			},
+		},
+		BlobThresholdBytes: envInt("BLOB_THRESHOLD_BYTES", 256<<10),
+
 		WorkerID:     envOr("WORKER_ID", "worker-1"),
 		Workers:      envInt("WORKERS", 8),
 		LeaseSeconds: envInt("LEASE_SECONDS", 30),
@@ -49,3 +99,14 @@ func envInt(key string, def int) int {
 	}
 	return i
 }
+
+// envList reads a comma-separated list, dropping blank entries.
+func envList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
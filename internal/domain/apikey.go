@@ -0,0 +1,96 @@
+package domain
+
+import "time"
+
+type Scope string
+
+const (
+	ScopeJobsCreate Scope = "jobs:create"
+	ScopeJobsRead   Scope = "jobs:read"
+	// ScopeJobsAdmin implies every other jobs:* scope within the key's tenant,
+	// except ScopeJobsPayload.
+	ScopeJobsAdmin Scope = "jobs:admin"
+	// ScopeJobsPayload shows job payloads in API responses; without it they
+	// are redacted. It must be granted explicitly.
+	ScopeJobsPayload Scope = "jobs:payload"
+	// ScopeTenantsAdmin lets operators act across tenants (keys, quotas).
+	ScopeTenantsAdmin Scope = "tenants:admin"
+)
+
+// ValidScope reports whether s is a known scope.
+func ValidScope(s Scope) bool {
+	switch s {
+	case ScopeJobsCreate, ScopeJobsRead, ScopeJobsAdmin, ScopeJobsPayload, ScopeTenantsAdmin:
+		return true
+	}
+	return false
+}
+
+// APIKey is an authenticated caller. Only the SHA-256 of the key is stored.
+type APIKey struct {
+	ID       string  `json:"id"`
+	TenantID string  `json:"tenant_id"`
+	Name     string  `json:"name"`
+	Prefix   string  `json:"prefix"`
+	Scopes   []Scope `json:"scopes"`
+
+	// Empty means unrestricted.
+	AllowedTypes  []string `json:"allowed_types,omitempty"`
+	AllowedQueues []string `json:"allowed_queues,omitempty"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k *APIKey) HasScope(s Scope) bool {
+	for _, have := range k.Scopes {
+		if have == s {
+			return true
+		}
+		if have == ScopeJobsAdmin && s != ScopeTenantsAdmin && s != ScopeJobsPayload {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the key may act on jobs of this type and queue.
+func (k *APIKey) Allows(jobType, queue string) bool {
+	return allowed(k.AllowedTypes, jobType) && allowed(k.AllowedQueues, queue)
+}
+
+// Within narrows the type and queue restrictions asked for a new key to
+// those of k: an empty list inherits k's. It reports false if the request
+// lists a type or queue that k may not act on.
+func (k *APIKey) Within(types, queues []string) ([]string, []string, bool) {
+	types, ok := within(k.AllowedTypes, types)
+	if !ok {
+		return nil, nil, false
+	}
+	queues, ok = within(k.AllowedQueues, queues)
+	return types, queues, ok
+}
+
+func within(have, want []string) ([]string, bool) {
+	if len(want) == 0 {
+		return have, true
+	}
+	for _, v := range want {
+		if !allowed(have, v) {
+			return nil, false
+		}
+	}
+	return want, true
+}
+
+func allowed(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
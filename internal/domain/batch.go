@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MaxBatchSize bounds the jobs of one batch.
+const MaxBatchSize = 10_000
+
+// Batch is a group of jobs created together and tracked as one unit.
+// Failed counts every child that ended in a terminal state other than SUCCESS.
+type Batch struct {
+	ID        string `json:"id"`
+	TenantID  string `json:"tenant_id"`
+	Total     int    `json:"total"`
+	Pending   int    `json:"pending"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	// CallbackJobID is set once every child finished and the callback job
+	// was enqueued.
+	CallbackJobID *string    `json:"callback_job_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+}
+
+// BatchSpec describes a batch to be created. Callback, if set, is enqueued
+// once every job is terminal, with a payload of the form BatchCallbackPayload.
+type BatchSpec struct {
+	ID       string
+	TenantID string
+	APIKeyID *string
+	Jobs     []JobSpec
+	Callback *JobSpec
+}
+
+// BatchCallbackPayload is the payload of a batch's callback job; Payload is
+// the one given when the batch was created.
+type BatchCallbackPayload struct {
+	BatchID   string          `json:"batch_id"`
+	Total     int             `json:"total"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// ConcurrencyLimit caps how many jobs of one type may be RUNNING at once,
+// across all workers and tenants. Either cap may be nil.
+type ConcurrencyLimit struct {
+	JobType string `json:"job_type"`
+	// MaxRunning caps RUNNING jobs of the type as a whole.
+	MaxRunning *int `json:"max_running,omitempty"`
+	// MaxPerKey caps RUNNING jobs per concurrency_key; jobs without a key
+	// are not affected. Defaults to 1 when unset.
+	MaxPerKey *int      `json:"max_per_key,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
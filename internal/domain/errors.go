@@ -5,4 +5,29 @@ import "errors"
 var (
 	ErrInvalidInput = errors.New("invalid_input")
 	ErrNotFound     = errors.New("not_found")
+	ErrConflict     = errors.New("conflict")
 )
+
+// FieldError points at one invalid field of a request.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError rejects a request for breaking a business rule. Code is a
+// stable snake_case identifier such as "invalid_priority". It wraps
+// ErrInvalidInput.
+type ValidationError struct {
+	Code    string
+	Message string
+	Fields  []FieldError
+}
+
+func (e *ValidationError) Error() string { return e.Code + ": " + e.Message }
+
+func (e *ValidationError) Unwrap() error { return ErrInvalidInput }
+
+// Invalid builds a ValidationError for a single field.
+func Invalid(code, field, message string) *ValidationError {
+	return &ValidationError{Code: code, Message: field + " " + message, Fields: []FieldError{{Field: field, Message: message}}}
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+type EventType string
+
+const (
+	EventCreated        EventType = "job.created"
+	EventReplaced       EventType = "job.payload_replaced"
+	EventUnblocked      EventType = "job.unblocked"
+	EventClaimed        EventType = "job.claimed"
+	EventRetryScheduled EventType = "job.retry_scheduled"
+	EventSucceeded      EventType = "job.succeeded"
+	EventFailed         EventType = "job.failed"
+	EventCancelled      EventType = "job.cancelled"
+	EventRetried        EventType = "job.retried"
+
+	EventCompensating       EventType = "job.compensating"
+	EventCompensationRetry  EventType = "job.compensation_retry_scheduled"
+	EventCompensated        EventType = "job.compensated"
+	EventCompensationFailed EventType = "job.compensation_failed"
+)
+
+// JobEvent is one entry of the durable job lifecycle log.
+// ID is a monotonically increasing sequence shared by all jobs.
+type JobEvent struct {
+	ID        int64     `json:"id"`
+	Type      EventType `json:"type"`
+	JobID     string    `json:"job_id"`
+	TenantID  string    `json:"tenant_id"`
+	JobType   string    `json:"job_type"`
+	Queue     string    `json:"queue"`
+	Status    JobStatus `json:"status"`
+	Attempts  int       `json:"attempts"`
+	Message   *string   `json:"message,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EventFilter narrows an event listing; empty fields match everything.
+type EventFilter struct {
+	// TenantID is required; events never cross tenants.
+	TenantID string
+	JobID    string
+	JobType  string
+	Queue    string
+}
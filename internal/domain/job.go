@@ -8,28 +8,97 @@ import (
 type JobStatus string
 
 const (
-	StatusPending JobStatus = "PENDING"
-	StatusRunning JobStatus = "RUNNING"
-	StatusSuccess JobStatus = "SUCCESS"
-	StatusFailed  JobStatus = "FAILED"
+	StatusPending   JobStatus = "PENDING"
+	StatusBlocked   JobStatus = "BLOCKED" // waiting for parents (see DependsOn)
+	StatusRunning   JobStatus = "RUNNING"
+	StatusSuccess   JobStatus = "SUCCESS"
+	StatusFailed    JobStatus = "FAILED"
+	StatusCancelled JobStatus = "CANCELLED"
+
+	// Saga states: after a terminal failure, compensations of completed steps
+	// run in reverse order.
+	StatusCompensating       JobStatus = "COMPENSATING"
+	StatusCompensated        JobStatus = "COMPENSATED"
+	StatusCompensationFailed JobStatus = "COMPENSATION_FAILED"
+)
+
+// Terminal reports whether a job in status s will never run again on its own.
+func (s JobStatus) Terminal() bool {
+	switch s {
+	case StatusSuccess, StatusFailed, StatusCancelled, StatusCompensated, StatusCompensationFailed:
+		return true
+	}
+	return false
+}
+
+// DefaultQueue is used when a job is created without an explicit queue.
+const DefaultQueue = "default"
+
+// Priority bounds. Within a tenant, higher-priority due jobs are claimed first.
+const (
+	MinPriority = -100
+	MaxPriority = 100
 )
 
 // Job is the canonical model used across API, service, repo, worker.
 type Job struct {
-	ID string `json:"id"`
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id"`
 
 	Type    string          `json:"type"`
+	Queue   string          `json:"queue"`
 	Payload json.RawMessage `json:"payload"` // Prevent base64 encoding
+	// PayloadVersion is the shape of Payload, starting at 1.
+	PayloadVersion int `json:"payload_version"`
+	// PayloadRedacted is set in API responses when Payload was withheld.
+	PayloadRedacted bool `json:"payload_redacted,omitempty"`
+	// PayloadRef is the blob-store key of an offloaded payload; Payload is
+	// then null until loaded from the store.
+	PayloadRef *string `json:"-"`
+	// PayloadEncoding is how a sealed or offloaded Payload was compressed
+	// before storage (codec.Identity or codec.Gzip); it is decoded after the
+	// payload is loaded and opened. Plaintext inline payloads are decoded by
+	// the repository.
+	PayloadEncoding string `json:"-"`
+	// PayloadOffloaded is set in API responses for offloaded payloads. Job
+	// lists leave their payload null; GET /jobs/{id} returns it.
+	PayloadOffloaded bool `json:"payload_offloaded,omitempty"`
 
-	Status JobStatus `json:"status"`
+	Status   JobStatus `json:"status"`
+	Priority int       `json:"priority"`
 
 	// Retry
 	Attempts    int        `json:"attempts"`
 	MaxAttempts int        `json:"max_attempts"`
 	NextRunAt   *time.Time `json:"next_run_at,omitempty"`
 
+	// Failed compensation runs (saga rollback)
+	CompensationAttempts int `json:"compensation_attempts,omitempty"`
+
 	// Idempotency
-	IdempotencyKey *string `json:"idempotency_key,omitempty"`
+	IdempotencyKey         *string    `json:"idempotency_key,omitempty"`
+	IdempotencyExpiresAt   *time.Time `json:"idempotency_expires_at,omitempty"`
+	IdempotencyFingerprint *string    `json:"-"`
+
+	// Completion webhook
+	CallbackURL *string `json:"callback_url,omitempty"`
+
+	// Jobs sharing a type and concurrency key are capped cluster-wide
+	ConcurrencyKey *string `json:"concurrency_key,omitempty"`
+
+	// Content-based uniqueness (see UniqueSpec)
+	UniqueKey *string `json:"unique_key,omitempty"`
+
+	// Fan-out batch this job belongs to
+	BatchID *string `json:"batch_id,omitempty"`
+
+	// Dependencies
+	WorkflowID      *string             `json:"workflow_id,omitempty"`
+	WorkflowNode    *string             `json:"workflow_node,omitempty"`
+	OnParentFailure ParentFailurePolicy `json:"on_parent_failure"`
+
+	// Audit: API key that created the job
+	APIKeyID *string `json:"api_key_id,omitempty"`
 
 	// Execution tracking
 	StartedAt    *time.Time `json:"started_at,omitempty"`
@@ -44,3 +113,60 @@ type Job struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// JobSpec describes a job to be enqueued.
+type JobSpec struct {
+	ID             string
+	TenantID       string
+	Type           string
+	Queue          string
+	Payload        json.RawMessage
+	PayloadVersion int // 0 means 1
+	MaxAttempts    int
+	Priority       int
+	RunAt          *time.Time // first run no earlier than this; nil = now
+	IdempotencyKey *string
+	CallbackURL    *string
+	ConcurrencyKey *string
+	Unique         *UniqueSpec
+	APIKeyID       *string
+
+	BatchID *string
+
+	// DependsOn lists parent job IDs that must succeed before the job runs.
+	DependsOn       []string
+	OnParentFailure ParentFailurePolicy
+	WorkflowID      *string
+	WorkflowNode    *string
+
+	// IdempotencyTTL bounds how long IdempotencyKey is reserved; zero keeps it forever.
+	IdempotencyTTL time.Duration
+}
+
+// JobFilter narrows a job listing; empty fields match everything.
+type JobFilter struct {
+	// TenantID is required; listings never cross tenants.
+	TenantID string
+	Status   JobStatus
+	Type     string
+	Queue    string
+
+	// Before is a cursor: only jobs with a smaller ID are listed.
+	Before string
+}
+
+// BulkItemResult is the outcome of one item of a bulk create. Err is set
+// instead of Outcome when the item was rejected; other items are unaffected.
+type BulkItemResult struct {
+	JobID   string
+	Outcome CreateOutcome
+	Err     error
+}
+
+// PayloadVersionCount is how many queued (PENDING or BLOCKED) jobs of a type
+// carry a given payload version.
+type PayloadVersionCount struct {
+	Type           string `json:"type"`
+	PayloadVersion int    `json:"payload_version"`
+	Queued         int    `json:"queued"`
+}
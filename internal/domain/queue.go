@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// QueueStats summarizes one queue of a tenant. A paused queue keeps
+// accepting jobs but none of them are claimed until it is resumed.
+type QueueStats struct {
+	Queue    string            `json:"queue"`
+	Paused   bool              `json:"paused"`
+	PausedAt *time.Time        `json:"paused_at,omitempty"`
+	Counts   map[JobStatus]int `json:"counts"`
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// RateLimit is a cluster-wide token bucket for one job type, optionally
+// narrowed to one queue. Each claimed job consumes one token.
+type RateLimit struct {
+	JobType string `json:"job_type"`
+	// Queue is empty when the limit applies to the type in every queue.
+	Queue      string    `json:"queue,omitempty"`
+	RatePerSec float64   `json:"rate_per_sec"`
+	Burst      int       `json:"burst"`
+	Tokens     float64   `json:"tokens"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// RetentionRule deletes jobs that have been in Status for longer than TTL,
+// counted from completed_at. A rule with a Type overrides the rule for the
+// same status without one; TTL 0 keeps such jobs forever.
+type RetentionRule struct {
+	Status JobStatus     `json:"status"`
+	Type   string        `json:"type,omitempty"`
+	TTL    time.Duration `json:"-"`
+}
+
+// RetentionRuleStats is what one rule deleted in a sweep.
+type RetentionRuleStats struct {
+	Status  JobStatus `json:"status"`
+	Type    string    `json:"type,omitempty"`
+	TTL     string    `json:"ttl"`
+	Deleted int       `json:"deleted"`
+}
+
+// RetentionRun reports one retention sweep.
+type RetentionRun struct {
+	WorkerID   string    `json:"worker_id"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Deleted    int       `json:"deleted"`
+	// Archived counts jobs written to ArchiveFile. A job retried between
+	// archiving and deletion is archived but not deleted.
+	Archived    int                  `json:"archived"`
+	ArchiveFile string               `json:"archive_file,omitempty"`
+	Rules       []RetentionRuleStats `json:"rules"`
+	Error       string               `json:"error,omitempty"`
+}
+
+// ArchivedJob is one line of a retention archive: the job as stored, with
+// its execution records. Payload is the stored form, loaded from the blob
+// store if it was offloaded; if it is sealed, StoredEncoding tells how to
+// decode it once opened.
+type ArchivedJob struct {
+	Job
+	StoredEncoding string              `json:"payload_encoding,omitempty"`
+	Executions     []ArchivedExecution `json:"executions,omitempty"`
+}
+
+// ArchivedExecution is a job_executions row of an archived job.
+type ArchivedExecution struct {
+	StepKey    string          `json:"step_key"`
+	ResultHash *string         `json:"result_hash,omitempty"`
+	Output     json.RawMessage `json:"output,omitempty"`
+	ExecutedAt time.Time       `json:"executed_at"`
+}
@@ -0,0 +1,21 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Where a payload schema comes from.
+const (
+	SchemaSourceConfig = "config"
+	SchemaSourceAPI    = "api"
+)
+
+// PayloadSchema is the JSON Schema that payloads of one job type must match.
+// Schemas set through the API take precedence over the config file.
+type PayloadSchema struct {
+	JobType   string          `json:"job_type"`
+	Schema    json.RawMessage `json:"schema"`
+	Source    string          `json:"source"`
+	UpdatedAt *time.Time      `json:"updated_at,omitempty"`
+}
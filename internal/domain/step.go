@@ -0,0 +1,11 @@
+package domain
+
+import "encoding/json"
+
+// StepRecord is a completed handler step saved in job_executions.
+type StepRecord struct {
+	Name   string
+	Output json.RawMessage
+	// Compensated is set once the step's compensation has succeeded.
+	Compensated bool
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultTenant owns jobs created by the bootstrap key and rows that predate tenancy.
+const DefaultTenant = "default"
+
+// ErrQuotaExceeded is returned when a tenant is over its queued-job quota.
+var ErrQuotaExceeded = errors.New("quota_exceeded")
+
+// TenantQuota caps a tenant's share of the scheduler. Nil means unlimited.
+type TenantQuota struct {
+	TenantID   string    `json:"tenant_id"`
+	MaxRunning *int      `json:"max_running,omitempty"`
+	MaxQueued  *int      `json:"max_queued,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
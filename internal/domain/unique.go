@@ -0,0 +1,85 @@
+package domain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// UniquePolicy decides what happens when a job is created while another job
+// with the same unique key still blocks it.
+type UniquePolicy string
+
+const (
+	// UniqueReturnExisting leaves the existing job alone and returns it.
+	UniqueReturnExisting UniquePolicy = "return_existing"
+	// UniqueReplace overwrites the existing job's payload if it has not
+	// started yet (PENDING or BLOCKED); a started job is returned unchanged.
+	UniqueReplace UniquePolicy = "replace"
+)
+
+// UniqueSpec makes a job unique per (tenant, type, Key).
+//
+// With a zero Window, an existing PENDING, BLOCKED or RUNNING job blocks new ones.
+// With a Window, any job created within the last Window blocks new ones,
+// whatever its status, which debounces noisy producers.
+type UniqueSpec struct {
+	Key    string
+	Policy UniquePolicy
+	Window time.Duration
+}
+
+// CreateOutcome says how a create request was satisfied.
+type CreateOutcome string
+
+const (
+	OutcomeCreated CreateOutcome = "created"
+	// OutcomeReplayed: an identical request with the same idempotency key
+	// created the job earlier.
+	OutcomeReplayed CreateOutcome = "replayed"
+	// OutcomeDeduplicated: the unique key matched an existing job, which
+	// was returned unchanged.
+	OutcomeDeduplicated CreateOutcome = "deduplicated"
+	// OutcomeReplaced: the unique key matched a job that had not started,
+	// and its payload was overwritten.
+	OutcomeReplaced CreateOutcome = "replaced"
+)
+
+// MaxUniqueKeyLen bounds caller-provided unique keys.
+const MaxUniqueKeyLen = 255
+
+// UniqueKeyFromFields derives a unique key from top-level payload fields, so
+// "one pending reindex per account_id" needs no key from the caller. Values
+// are compared as canonical JSON; every field must be present.
+func UniqueKeyFromFields(payload json.RawMessage, fields []string) (string, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &obj); err != nil {
+		return "", fmt.Errorf("%w: unique fields need a JSON object payload", ErrInvalidInput)
+	}
+
+	picked := make(map[string]any, len(fields))
+	for _, f := range fields {
+		raw, ok := obj[f]
+		if !ok {
+			return "", fmt.Errorf("%w: unique field %q missing from payload", ErrInvalidInput, f)
+		}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return "", fmt.Errorf("%w: unique field %q: %v", ErrInvalidInput, f, err)
+		}
+		picked[f] = v
+	}
+
+	// encoding/json sorts map keys, so field order does not matter.
+	b, err := json.Marshal(picked)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return "fields:" + hex.EncodeToString(sum[:]), nil
+}
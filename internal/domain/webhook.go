@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "PENDING"
+	DeliveryDelivered DeliveryStatus = "DELIVERED"
+	DeliveryFailed    DeliveryStatus = "FAILED"
+)
+
+// JobNotification is the JSON body POSTed to a job's callback_url once the
+// job reaches a terminal state.
+type JobNotification struct {
+	Event        EventType  `json:"event"`
+	JobID        string     `json:"job_id"`
+	Type         string     `json:"type"`
+	Queue        string     `json:"queue"`
+	Status       JobStatus  `json:"status"`
+	Attempts     int        `json:"attempts"`
+	ErrorMessage *string    `json:"error_message,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+// WebhookDelivery is one outbox row for a callback notification.
+type WebhookDelivery struct {
+	ID     int64          `json:"id"`
+	JobID  string         `json:"job_id"`
+	URL    string         `json:"url"`
+	Body   []byte         `json:"-"`
+	Status DeliveryStatus `json:"status"`
+
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastCode      *int       `json:"last_status_code,omitempty"`
+	LastError     *string    `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DeliveryFilter narrows a delivery listing; empty fields match everything.
+type DeliveryFilter struct {
+	// TenantID limits results to one tenant; empty means all (operators only).
+	TenantID string
+	JobID    string
+	Status   DeliveryStatus
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrDependencyFailed is returned when a job is created with a parent that
+// already failed or was cancelled and its policy would cascade that failure.
+var ErrDependencyFailed = errors.New("dependency_failed")
+
+// ParentFailurePolicy decides what a BLOCKED job does when a parent ends in
+// FAILED or CANCELLED.
+type ParentFailurePolicy string
+
+const (
+	// ParentFailureCascade cancels the job, and in turn its own dependents.
+	ParentFailureCascade ParentFailurePolicy = "cascade"
+	// ParentFailureRun treats the parent as settled and runs the job anyway,
+	// e.g. for cleanup or notification nodes.
+	ParentFailureRun ParentFailurePolicy = "run"
+)
+
+// Workflow is a DAG of jobs submitted together.
+type Workflow struct {
+	ID       string `json:"id"`
+	TenantID string `json:"tenant_id"`
+	Name     string `json:"name,omitempty"`
+	// Status is derived from the nodes; see WorkflowStatus.
+	Status    JobStatus      `json:"status"`
+	Nodes     []WorkflowNode `json:"nodes"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// WorkflowNode is one job of a workflow, with its parents given as node keys.
+type WorkflowNode struct {
+	Key          string     `json:"key"`
+	JobID        string     `json:"job_id"`
+	Type         string     `json:"type"`
+	Queue        string     `json:"queue"`
+	Status       JobStatus  `json:"status"`
+	Attempts     int        `json:"attempts"`
+	DependsOn    []string   `json:"depends_on"`
+	ErrorMessage *string    `json:"error_message,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
+
+// WorkflowSpec describes a workflow to be created. Nodes must be in
+// topological order, with DependsOn holding the parents' job IDs.
+type WorkflowSpec struct {
+	ID       string
+	TenantID string
+	Name     string
+	APIKeyID *string
+	Nodes    []JobSpec
+}
+
+// WorkflowStatus summarizes node statuses: RUNNING while any node runs,
+// PENDING while nodes wait but none runs, SUCCESS when all succeeded and
+// FAILED once every node finished and at least one did not succeed.
+func WorkflowStatus(nodes []WorkflowNode) JobStatus {
+	status := StatusSuccess
+	for _, n := range nodes {
+		switch n.Status {
+		case StatusRunning, StatusCompensating:
+			return StatusRunning
+		case StatusPending, StatusBlocked:
+			status = StatusPending
+		case StatusFailed, StatusCancelled, StatusCompensated, StatusCompensationFailed:
+			if status == StatusSuccess {
+				status = StatusFailed
+			}
+		}
+	}
+	return status
+}
@@ -0,0 +1,442 @@
+// Package jsonschema validates JSON documents against the subset of JSON
+// Schema that job payloads need: type, properties, required,
+// additionalProperties, items, enum, const, the numeric and length bounds,
+// and pattern. Other keywords ($schema, title, description, format, ...)
+// are accepted and ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Schema is a compiled schema, safe for concurrent use.
+type Schema struct {
+	types      []string
+	properties map[string]*Schema
+	required   []string
+	// additional is nil when any extra property is allowed.
+	additional *Schema
+	noExtra    bool
+	items      *Schema
+	enum       []any
+	constant   any
+	hasConst   bool
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+	minLength, maxLength               *int
+	minItems, maxItems                 *int
+	pattern                            *regexp.Regexp
+}
+
+// Violation is one place where a document does not match its schema.
+type Violation struct {
+	// Path locates the value, e.g. "items[2].sku"; empty for the root.
+	Path    string
+	Message string
+}
+
+var knownTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// Compile parses a schema document.
+func Compile(raw json.RawMessage) (*Schema, error) {
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+	return compile(doc, "")
+}
+
+func compile(doc any, at string) (*Schema, error) {
+	if b, ok := doc.(bool); ok {
+		// true accepts anything; false accepts nothing.
+		if b {
+			return &Schema{}, nil
+		}
+		return &Schema{enum: []any{}}, nil
+	}
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: schema must be an object", where(at))
+	}
+
+	s := &Schema{}
+	switch t := m["type"].(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []any:
+		for _, v := range t {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: type must be a string or a list of strings", where(at))
+			}
+			s.types = append(s.types, name)
+		}
+	default:
+		return nil, fmt.Errorf("%s: type must be a string or a list of strings", where(at))
+	}
+	for _, t := range s.types {
+		if !knownTypes[t] {
+			return nil, fmt.Errorf("%s: unknown type %q", where(at), t)
+		}
+	}
+
+	if props, ok := m["properties"]; ok {
+		pm, ok := props.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: properties must be an object", where(at))
+		}
+		s.properties = make(map[string]*Schema, len(pm))
+		for name, sub := range pm {
+			c, err := compile(sub, join(at, name))
+			if err != nil {
+				return nil, err
+			}
+			s.properties[name] = c
+		}
+	}
+
+	if req, ok := m["required"]; ok {
+		list, ok := req.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: required must be a list of strings", where(at))
+		}
+		for _, v := range list {
+			name, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: required must be a list of strings", where(at))
+			}
+			s.required = append(s.required, name)
+		}
+	}
+
+	switch ap := m["additionalProperties"].(type) {
+	case nil:
+	case bool:
+		s.noExtra = !ap
+	default:
+		c, err := compile(ap, at)
+		if err != nil {
+			return nil, err
+		}
+		s.additional = c
+	}
+
+	if items, ok := m["items"]; ok {
+		c, err := compile(items, at+"[]")
+		if err != nil {
+			return nil, err
+		}
+		s.items = c
+	}
+
+	if e, ok := m["enum"]; ok {
+		list, ok := e.([]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: enum must be a list", where(at))
+		}
+		s.enum = list
+	}
+	if c, ok := m["const"]; ok {
+		s.constant, s.hasConst = c, true
+	}
+
+	var err error
+	num := func(key string) *float64 {
+		v, ok := m[key]
+		if !ok || err != nil {
+			return nil
+		}
+		n, ok := v.(json.Number)
+		if !ok {
+			err = fmt.Errorf("%s: %s must be a number", where(at), key)
+			return nil
+		}
+		f, _ := n.Float64()
+		return &f
+	}
+	count := func(key string) *int {
+		f := num(key)
+		if f == nil {
+			return nil
+		}
+		if *f < 0 || *f != math.Trunc(*f) {
+			err = fmt.Errorf("%s: %s must be a non-negative integer", where(at), key)
+			return nil
+		}
+		n := int(*f)
+		return &n
+	}
+	s.minimum, s.maximum = num("minimum"), num("maximum")
+	s.exclusiveMinimum, s.exclusiveMaximum = num("exclusiveMinimum"), num("exclusiveMaximum")
+	s.minLength, s.maxLength = count("minLength"), count("maxLength")
+	s.minItems, s.maxItems = count("minItems"), count("maxItems")
+	if err != nil {
+		return nil, err
+	}
+
+	if p, ok := m["pattern"]; ok {
+		str, ok := p.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: pattern must be a string", where(at))
+		}
+		re, err := regexp.Compile(str)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern: %w", where(at), err)
+		}
+		s.pattern = re
+	}
+	return s, nil
+}
+
+// Validate checks doc against s and returns every violation found, in a
+// stable order. An invalid JSON document is reported as a single violation.
+func (s *Schema) Validate(doc json.RawMessage) []Violation {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return []Violation{{Message: "is not valid JSON"}}
+	}
+	var out []Violation
+	s.check(v, "", &out)
+	return out
+}
+
+// ErrMismatch is wrapped by errors describing a document that does not
+// match its schema.
+var ErrMismatch = errors.New("payload does not match schema")
+
+// Check is Validate as an error: nil, or ErrMismatch wrapped with the first
+// few violations.
+func (s *Schema) Check(doc json.RawMessage) error {
+	vs := s.Validate(doc)
+	if len(vs) == 0 {
+		return nil
+	}
+	msg := ""
+	for i, v := range vs {
+		if i == 3 {
+			msg += fmt.Sprintf("; and %d more", len(vs)-i)
+			break
+		}
+		if i > 0 {
+			msg += "; "
+		}
+		msg += where(v.Path) + " " + v.Message
+	}
+	return fmt.Errorf("%w: %s", ErrMismatch, msg)
+}
+
+func (s *Schema) check(v any, at string, out *[]Violation) {
+	fail := func(format string, args ...any) {
+		*out = append(*out, Violation{Path: at, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.types) > 0 && !s.typeMatches(v) {
+		if len(s.types) == 1 {
+			fail("must be of type %s", s.types[0])
+		} else {
+			fail("must be one of the types %v", s.types)
+		}
+		return
+	}
+	if s.enum != nil && !containsValue(s.enum, v) {
+		if len(s.enum) == 0 {
+			fail("is not allowed")
+		} else {
+			fail("must be one of %s", render(s.enum))
+		}
+		return
+	}
+	if s.hasConst && !equal(s.constant, v) {
+		fail("must be %s", render(s.constant))
+		return
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := val[name]; !ok {
+				*out = append(*out, Violation{Path: join(at, name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(val))
+		for name := range val {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := s.properties[name]; ok {
+				sub.check(val[name], join(at, name), out)
+				continue
+			}
+			switch {
+			case s.noExtra:
+				*out = append(*out, Violation{Path: join(at, name), Message: "is not an allowed property"})
+			case s.additional != nil:
+				s.additional.check(val[name], join(at, name), out)
+			}
+		}
+
+	case []any:
+		if s.minItems != nil && len(val) < *s.minItems {
+			fail("must have at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(val) > *s.maxItems {
+			fail("must have at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range val {
+				s.items.check(item, at+"["+strconv.Itoa(i)+"]", out)
+			}
+		}
+
+	case string:
+		n := utf8.RuneCountInString(val)
+		if s.minLength != nil && n < *s.minLength {
+			fail("must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			fail("must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(val) {
+			fail("must match %q", s.pattern.String())
+		}
+
+	case json.Number:
+		f, _ := val.Float64()
+		if s.minimum != nil && f < *s.minimum {
+			fail("must be >= %v", *s.minimum)
+		}
+		if s.maximum != nil && f > *s.maximum {
+			fail("must be <= %v", *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && f <= *s.exclusiveMinimum {
+			fail("must be > %v", *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && f >= *s.exclusiveMaximum {
+			fail("must be < %v", *s.exclusiveMaximum)
+		}
+	}
+}
+
+func (s *Schema) typeMatches(v any) bool {
+	for _, t := range s.types {
+		switch val := v.(type) {
+		case map[string]any:
+			if t == "object" {
+				return true
+			}
+		case []any:
+			if t == "array" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			}
+			if t == "integer" {
+				if f, err := val.Float64(); err == nil && f == math.Trunc(f) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func containsValue(list []any, v any) bool {
+	for _, e := range list {
+		if equal(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares decoded JSON values; numbers compare by value, so 1 and
+// 1.0 are equal.
+func equal(a, b any) bool {
+	if an, ok := a.(json.Number); ok {
+		bn, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, _ := an.Float64()
+		bf, _ := bn.Float64()
+		return af == bf
+	}
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, x := range av {
+			y, ok := bv[k]
+			if !ok || !equal(x, y) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+func render(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func join(at, name string) string {
+	if at == "" {
+		return name
+	}
+	return at + "." + name
+}
+
+func where(at string) string {
+	if at == "" {
+		return "document"
+	}
+	return at
+}
@@ -0,0 +1,228 @@
+// Package keyring encrypts job payloads at rest with AES-256-GCM envelopes.
+//
+// Each payload gets a fresh data key. The payload is sealed with the data
+// key, and the data key is sealed ("wrapped") with a key from the keyring,
+// whose ID is stored alongside. Rotating keys therefore only rewraps the
+// data keys; payloads are not re-encrypted.
+//
+// A sealed payload is itself a JSON object, so it fits the jobs.payload
+// column:
+//
+//	{"$enc": "aes-256-gcm", "kid": "2026-10", "dek": "...", "nonce": "...", "data": "..."}
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+const algorithm = "aes-256-gcm"
+
+// ErrUnknownKey is returned when a payload is sealed under a key ID the
+// keyring does not hold.
+var ErrUnknownKey = errors.New("keyring: unknown key")
+
+// Keyring holds the key-encryption keys by ID. New payloads are sealed with
+// the primary key; the others are kept to open older payloads.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// file is the on-disk keyring: base64-encoded 32-byte keys by ID.
+//
+//	{"primary": "2026-10", "keys": {"2026-04": "...", "2026-10": "..."}}
+type file struct {
+	Primary string            `json:"primary"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// Load reads a keyring file.
+func Load(path string) (*Keyring, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("keyring %s: %w", path, err)
+	}
+	keys := make(map[string][]byte, len(f.Keys))
+	for id, enc := range f.Keys {
+		k, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil {
+			return nil, fmt.Errorf("keyring %s: key %s: %w", path, id, err)
+		}
+		keys[id] = k
+	}
+	kr, err := New(f.Primary, keys)
+	if err != nil {
+		return nil, fmt.Errorf("keyring %s: %w", path, err)
+	}
+	return kr, nil
+}
+
+// New builds a keyring from raw 32-byte keys. primary must be one of them.
+func New(primary string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key %q not in keyring", primary)
+	}
+	kr := &Keyring{primary: primary, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, k := range keys {
+		if id == "" {
+			return nil, errors.New("empty key ID")
+		}
+		if len(k) != 32 {
+			return nil, fmt.Errorf("key %s: want 32 bytes, got %d", id, len(k))
+		}
+		aead, err := newGCM(k)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", id, err)
+		}
+		kr.keys[id] = aead
+	}
+	return kr, nil
+}
+
+// Primary is the ID of the key new payloads are sealed with.
+func (k *Keyring) Primary() string { return k.primary }
+
+type envelope struct {
+	Enc   string `json:"$enc"`
+	KeyID string `json:"kid"`
+	DEK   []byte `json:"dek"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// Seal encrypts plaintext. aad binds the result to its context (the job ID),
+// so a sealed payload cannot be moved to another row.
+func (k *Keyring) Seal(plaintext []byte, aad string) (json.RawMessage, error) {
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, err
+	}
+	data, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	env := envelope{Enc: algorithm, KeyID: k.primary, Nonce: nonce(data)}
+	env.Data = data.Seal(nil, env.Nonce, plaintext, []byte(aad))
+	if env.DEK, err = k.wrap(k.primary, dek); err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
+
+// Open decrypts a sealed payload. Payloads that are not sealed are returned
+// as they are, so plaintext rows written before encryption was enabled keep
+// working.
+func (k *Keyring) Open(raw json.RawMessage, aad string) (json.RawMessage, error) {
+	env, ok := parse(raw)
+	if !ok {
+		return raw, nil
+	}
+	dek, err := k.unwrap(env.KeyID, env.DEK)
+	if err != nil {
+		return nil, err
+	}
+	data, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	out, err := data.Open(nil, env.Nonce, env.Data, []byte(aad))
+	if err != nil {
+		return nil, fmt.Errorf("keyring: open payload: %w", err)
+	}
+	return out, nil
+}
+
+// Rotate brings raw up to date: plaintext is sealed, and a payload sealed
+// under an older key has its data key rewrapped with the primary key.
+// changed is false if raw was already sealed under the primary key.
+func (k *Keyring) Rotate(raw json.RawMessage, aad string) (out json.RawMessage, changed bool, err error) {
+	env, ok := parse(raw)
+	if !ok {
+		out, err = k.Seal(raw, aad)
+		return out, err == nil, err
+	}
+	if env.KeyID == k.primary {
+		return raw, false, nil
+	}
+	dek, err := k.unwrap(env.KeyID, env.DEK)
+	if err != nil {
+		return nil, false, err
+	}
+	if env.DEK, err = k.wrap(k.primary, dek); err != nil {
+		return nil, false, err
+	}
+	env.KeyID = k.primary
+	out, err = json.Marshal(env)
+	return out, err == nil, err
+}
+
+// Sealed reports whether raw is a sealed payload.
+func Sealed(raw json.RawMessage) bool {
+	_, ok := parse(raw)
+	return ok
+}
+
+func parse(raw json.RawMessage) (envelope, bool) {
+	var env envelope
+	if len(raw) == 0 || raw[0] != '{' {
+		return env, false
+	}
+	if err := json.Unmarshal(raw, &env); err != nil || env.Enc != algorithm {
+		return env, false
+	}
+	return env, true
+}
+
+// wrap seals dek with key id; the key ID is the additional data, so a
+// wrapped key cannot be relabelled.
+func (k *Keyring) wrap(id string, dek []byte) ([]byte, error) {
+	aead, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	n := nonce(aead)
+	return aead.Seal(n, n, dek, []byte(id)), nil
+}
+
+func (k *Keyring) unwrap(id string, wrapped []byte) ([]byte, error) {
+	aead, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	ns := aead.NonceSize()
+	if len(wrapped) < ns {
+		return nil, errors.New("keyring: wrapped key too short")
+	}
+	dek, err := aead.Open(nil, wrapped[:ns], wrapped[ns:], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("keyring: unwrap data key: %w", err)
+	}
+	return dek, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func nonce(aead cipher.AEAD) []byte {
+	n := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(n); err != nil {
+		panic("keyring: " + err.Error())
+	}
+	return n
+}
@@ -0,0 +1,154 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+type APIKeyRepo struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepo(db *sql.DB) *APIKeyRepo {
+	return &APIKeyRepo{db: db}
+}
+
+func (r *APIKeyRepo) CreateAPIKey(ctx context.Context, key domain.APIKey, hash string) error {
+	if key.ID == "" || key.TenantID == "" || hash == "" {
+		return fmt.Errorf("id, tenant and hash are required")
+	}
+
+	scopes, err := json.Marshal(key.Scopes)
+	if err != nil {
+		return err
+	}
+	types, err := nullableJSONList(key.AllowedTypes)
+	if err != nil {
+		return err
+	}
+	queues, err := nullableJSONList(key.AllowedQueues)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO api_keys (id, tenant_id, name, prefix, key_hash, scopes, allowed_types, allowed_queues)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, key.ID, key.TenantID, key.Name, key.Prefix, hash, scopes, types, queues)
+	if err != nil {
+		return fmt.Errorf("insert api key: %w", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepo) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE key_hash = ? AND revoked_at IS NULL
+	`, hash)
+
+	k, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return k, err
+}
+
+func (r *APIKeyRepo) ListAPIKeys(ctx context.Context, tenantID string) ([]domain.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE ? = '' OR tenant_id = ?
+		ORDER BY created_at DESC
+	`, tenantID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepo) RevokeAPIKey(ctx context.Context, tenantID, id string, now time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, ?)
+		WHERE id = ? AND (? = '' OR tenant_id = ?)
+	`, now, id, tenantID, tenantID)
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	// RowsAffected is 0 for already-revoked keys too, so check existence.
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		var one int
+		err := r.db.QueryRowContext(ctx, `
+			SELECT 1 FROM api_keys WHERE id = ? AND (? = '' OR tenant_id = ?)
+		`, id, tenantID, tenantID).Scan(&one)
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+const apiKeyColumns = `
+	id, tenant_id, name, prefix,
+	CAST(scopes AS CHAR), CAST(allowed_types AS CHAR), CAST(allowed_queues AS CHAR),
+	created_at, revoked_at`
+
+func scanAPIKey(row jobRow) (*domain.APIKey, error) {
+	var k domain.APIKey
+	var scopes string
+	var types, queues sql.NullString
+	var revokedAt sql.NullTime
+
+	if err := row.Scan(
+		&k.ID, &k.TenantID, &k.Name, &k.Prefix,
+		&scopes, &types, &queues,
+		&k.CreatedAt, &revokedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(scopes), &k.Scopes); err != nil {
+		return nil, fmt.Errorf("decode scopes: %w", err)
+	}
+	if types.Valid {
+		if err := json.Unmarshal([]byte(types.String), &k.AllowedTypes); err != nil {
+			return nil, fmt.Errorf("decode allowed_types: %w", err)
+		}
+	}
+	if queues.Valid {
+		if err := json.Unmarshal([]byte(queues.String), &k.AllowedQueues); err != nil {
+			return nil, fmt.Errorf("decode allowed_queues: %w", err)
+		}
+	}
+	if revokedAt.Valid {
+		t := revokedAt.Time
+		k.RevokedAt = &t
+	}
+	return &k, nil
+}
+
+func nullableJSONList(list []string) (any, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(list)
+}
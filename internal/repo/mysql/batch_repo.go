@@ -0,0 +1,292 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+// insertChunkSize bounds the rows of one multi-row INSERT.
+const insertChunkSize = 500
+
+type BatchRepo struct {
+	db       *sql.DB
+	payloads PayloadOptions
+}
+
+func NewBatchRepo(db *sql.DB, payloads PayloadOptions) *BatchRepo {
+	return &BatchRepo{db: db, payloads: payloads}
+}
+
+func (r *BatchRepo) CreateBatch(ctx context.Context, spec domain.BatchSpec) (*domain.Batch, error) {
+	if spec.ID == "" {
+		return nil, fmt.Errorf("%w: id required", domain.ErrInvalidInput)
+	}
+	if len(spec.Jobs) == 0 || len(spec.Jobs) > domain.MaxBatchSize {
+		return nil, fmt.Errorf("%w: a batch needs 1 to %d jobs", domain.ErrInvalidInput, domain.MaxBatchSize)
+	}
+	if spec.TenantID == "" {
+		spec.TenantID = domain.DefaultTenant
+	}
+	for i := range spec.Jobs {
+		j := &spec.Jobs[i]
+		if j.ID == "" || j.Type == "" || len(j.Payload) == 0 {
+			return nil, fmt.Errorf("%w: job %d: id, type and payload required", domain.ErrInvalidInput, i)
+		}
+		j.TenantID = spec.TenantID
+		j.BatchID = &spec.ID
+		if j.APIKeyID == nil {
+			j.APIKeyID = spec.APIKeyID
+		}
+		if j.Queue == "" {
+			j.Queue = domain.DefaultQueue
+		}
+		if j.MaxAttempts <= 0 {
+			j.MaxAttempts = 3
+		}
+		if j.PayloadVersion <= 0 {
+			j.PayloadVersion = 1
+		}
+	}
+
+	var cbType, cbQueue *string
+	var cbPayload []byte
+	var cbMaxAttempts *int
+	if cb := spec.Callback; cb != nil {
+		if cb.Type == "" {
+			return nil, fmt.Errorf("%w: callback type required", domain.ErrInvalidInput)
+		}
+		queue := cb.Queue
+		if queue == "" {
+			queue = domain.DefaultQueue
+		}
+		cbType, cbQueue, cbPayload, cbMaxAttempts = &cb.Type, &queue, cb.Payload, &cb.MaxAttempts
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := checkQueuedQuota(ctx, tx, spec.TenantID, len(spec.Jobs)); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO batches (
+			id, tenant_id, total, pending, api_key_id,
+			callback_type, callback_queue, callback_payload, callback_max_attempts
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, spec.ID, spec.TenantID, len(spec.Jobs), len(spec.Jobs), spec.APIKeyID,
+		cbType, cbQueue, nullableBytes(cbPayload), cbMaxAttempts); err != nil {
+		return nil, fmt.Errorf("insert batch: %w", err)
+	}
+	for start := 0; start < len(spec.Jobs); start += insertChunkSize {
+		end := min(start+insertChunkSize, len(spec.Jobs))
+		if err := insertJobRows(ctx, tx, r.payloads, spec.Jobs[start:end]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetBatch(ctx, spec.TenantID, spec.ID)
+}
+
+func (r *BatchRepo) GetBatch(ctx context.Context, tenantID, id string) (*domain.Batch, error) {
+	var b domain.Batch
+	var callbackJobID sql.NullString
+	var completedAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, tenant_id, total, pending, succeeded, failed, callback_job_id, created_at, completed_at
+		FROM batches
+		WHERE id = ? AND tenant_id = ?
+	`, id, tenantID).Scan(&b.ID, &b.TenantID, &b.Total, &b.Pending, &b.Succeeded, &b.Failed,
+		&callbackJobID, &b.CreatedAt, &completedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if callbackJobID.Valid {
+		s := callbackJobID.String
+		b.CallbackJobID = &s
+	}
+	if completedAt.Valid {
+		t := completedAt.Time
+		b.CompletedAt = &t
+	}
+	return &b, nil
+}
+
+// insertJobRows writes simple PENDING jobs (no dependencies or unique keys)
+// with one multi-row INSERT, plus their creation events. Specs must already
+// carry their defaults.
+func insertJobRows(ctx context.Context, tx *sql.Tx, payloads PayloadOptions, specs []domain.JobSpec) (err error) {
+	values := make([]string, 0, len(specs))
+	args := make([]any, 0, 18*len(specs))
+	ids := make([]string, 0, len(specs))
+	var refs []*string
+	defer func() {
+		if err != nil {
+			payloads.discard(refs...)
+		}
+	}()
+	for _, s := range specs {
+		var fingerprint *string
+		var ttlMicros *int64
+		if s.IdempotencyKey != nil {
+			fp, err := requestFingerprint(s)
+			if err != nil {
+				return fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+			}
+			fingerprint = &fp
+			if s.IdempotencyTTL > 0 {
+				us := s.IdempotencyTTL.Microseconds()
+				ttlMicros = &us
+			}
+		}
+
+		payload, err := payloads.store(ctx, s.ID, s.Payload)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, payload.ref)
+
+		values = append(values, "(?, ?, ?, ?, ?, ?, ?, ?, 'PENDING', ?, 0, ?, COALESCE(?, NOW(6)), ?, ?, DATE_ADD(NOW(6), INTERVAL ? MICROSECOND), ?, ?, ?, ?)")
+		args = append(args, s.ID, s.TenantID, s.Type, s.Queue, payload.data, payload.encoding, payload.ref, s.PayloadVersion, s.Priority, s.MaxAttempts, s.RunAt,
+			s.IdempotencyKey, fingerprint, ttlMicros,
+			s.CallbackURL, s.ConcurrencyKey, s.APIKeyID, s.BatchID)
+		ids = append(ids, s.ID)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO jobs (
+			id, tenant_id, type, queue, payload, payload_encoding, payload_ref, payload_version, status, priority, attempts, max_attempts, next_run_at,
+			idempotency_key, idempotency_fingerprint, idempotency_expires_at,
+			callback_url, concurrency_key, api_key_id, batch_id
+		) VALUES `+strings.Join(values, ", "), args...); err != nil {
+		return fmt.Errorf("insert jobs: %w", err)
+	}
+
+	eventArgs := append([]any{string(domain.EventCreated)}, stringArgs(ids)...)
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO job_events (job_id, tenant_id, job_type, queue, event_type, status, attempts)
+		SELECT id, tenant_id, type, queue, ?, status, attempts
+		FROM jobs
+		WHERE id IN (`+placeholders(len(ids))+`)
+		ORDER BY id
+	`, eventArgs...); err != nil {
+		return fmt.Errorf("insert events: %w", err)
+	}
+	return nil
+}
+
+// settleBatch counts jobID's terminal status towards its batch, if any, and
+// enqueues the batch callback when it was the last child. Concurrent
+// completions of one batch serialize on the batch row.
+func settleBatch(ctx context.Context, tx *sql.Tx, payloads PayloadOptions, jobID string, succeeded bool, now time.Time) error {
+	var batchID sql.NullString
+	if err := tx.QueryRowContext(ctx, `
+		SELECT batch_id FROM jobs WHERE id = ?
+	`, jobID).Scan(&batchID); err != nil {
+		return fmt.Errorf("load batch id: %w", err)
+	}
+	if !batchID.Valid {
+		return nil
+	}
+
+	ok, failed := 0, 1
+	if succeeded {
+		ok, failed = 1, 0
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE batches
+		SET pending = pending - 1, succeeded = succeeded + ?, failed = failed + ?
+		WHERE id = ? AND pending > 0
+	`, ok, failed, batchID.String); err != nil {
+		return fmt.Errorf("update batch counters: %w", err)
+	}
+
+	var (
+		b                domain.BatchCallbackPayload
+		tenantID         string
+		pending          int
+		apiKeyID         sql.NullString
+		cbType, cbQueue  sql.NullString
+		cbPayload        sql.NullString
+		cbMaxAttempts    sql.NullInt64
+		completedAlready bool
+	)
+	if err := tx.QueryRowContext(ctx, `
+		SELECT tenant_id, total, pending, succeeded, failed, api_key_id,
+			callback_type, callback_queue, CAST(callback_payload AS CHAR), callback_max_attempts,
+			completed_at IS NOT NULL
+		FROM batches
+		WHERE id = ?
+		FOR UPDATE
+	`, batchID.String).Scan(&tenantID, &b.Total, &pending, &b.Succeeded, &b.Failed, &apiKeyID,
+		&cbType, &cbQueue, &cbPayload, &cbMaxAttempts, &completedAlready); err != nil {
+		return fmt.Errorf("load batch: %w", err)
+	}
+	if pending > 0 || completedAlready {
+		return nil
+	}
+
+	var callbackID *string
+	if cbType.Valid {
+		b.BatchID = batchID.String
+		if cbPayload.Valid {
+			b.Payload = json.RawMessage(cbPayload.String)
+		}
+		payload, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		id := batchID.String + "-callback"
+		spec := domain.JobSpec{
+			ID:              id,
+			TenantID:        tenantID,
+			Type:            cbType.String,
+			Queue:           cbQueue.String,
+			Payload:         payload,
+			PayloadVersion:  1,
+			MaxAttempts:     int(cbMaxAttempts.Int64),
+			OnParentFailure: domain.ParentFailureCascade,
+		}
+		if spec.MaxAttempts <= 0 {
+			spec.MaxAttempts = 3
+		}
+		if apiKeyID.Valid {
+			spec.APIKeyID = &apiKeyID.String
+		}
+		// The callback is part of work already admitted, so it skips the
+		// queued quota.
+		if err := insertJob(ctx, tx, payloads, spec, nil, nil); err != nil {
+			return fmt.Errorf("enqueue batch callback: %w", err)
+		}
+		callbackID = &id
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE batches SET completed_at = ?, callback_job_id = ? WHERE id = ?
+	`, now, callbackID, batchID.String); err != nil {
+		return fmt.Errorf("complete batch: %w", err)
+	}
+	return nil
+}
+
+func nullableBytes(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return b
+}
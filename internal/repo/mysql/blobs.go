@@ -0,0 +1,160 @@
+package mysqlrepo
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// blobWriteTimeout bounds a blob upload made while a transaction is open.
+const blobWriteTimeout = 30 * time.Second
+
+// storedPayload is a payload as written to a jobs row.
+type storedPayload struct {
+	data     []byte  // payload column
+	encoding string  // payload_encoding column
+	ref      *string // payload_ref column; nil if the payload is inline
+}
+
+// store compresses and seals payload for jobID and, if the result is over
+// the offload threshold, uploads it.
+//
+// The blob is written before the row, so a row never points at a missing
+// blob; callers discard it with o.discard if the row is not written.
+func (o PayloadOptions) store(ctx context.Context, jobID string, payload json.RawMessage) (storedPayload, error) {
+	encoded, encoding, err := o.encode(payload)
+	if err != nil {
+		return storedPayload{}, err
+	}
+	sealed, err := o.seal(jobID, encoded)
+	if err != nil {
+		return storedPayload{}, err
+	}
+	sp := storedPayload{data: sealed, encoding: encoding}
+	if o.Blobs == nil || o.BlobThreshold <= 0 || len(sealed) <= o.BlobThreshold {
+		return sp, nil
+	}
+
+	var suffix [8]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return storedPayload{}, err
+	}
+	key := "jobs/" + jobID + "/" + hex.EncodeToString(suffix[:])
+
+	ctx, cancel := context.WithTimeout(ctx, blobWriteTimeout)
+	defer cancel()
+	if err := o.Blobs.Put(ctx, key, sealed); err != nil {
+		return storedPayload{}, fmt.Errorf("offload payload: %w", err)
+	}
+	sp.data, sp.ref = []byte("null"), &key
+	return sp, nil
+}
+
+// load reads an offloaded payload from the blob store.
+func (o PayloadOptions) load(ctx context.Context, ref string) (json.RawMessage, error) {
+	if o.Blobs == nil {
+		return nil, fmt.Errorf("payload is offloaded to %s but no blob store is configured", ref)
+	}
+	b, err := o.Blobs.Get(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("load payload blob: %w", err)
+	}
+	return b, nil
+}
+
+// discard deletes blobs whose rows were not written, or no longer point
+// at them. Failures only leave an orphan behind, so they are logged.
+func (o PayloadOptions) discard(refs ...*string) {
+	if o.Blobs == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), blobWriteTimeout)
+	defer cancel()
+	for _, ref := range refs {
+		if ref == nil {
+			continue
+		}
+		if err := o.Blobs.Delete(ctx, *ref); err != nil {
+			log.Printf("discard payload blob %s: %v", *ref, err)
+		}
+	}
+}
+
+// PurgeJobs deletes the finished jobs among ids together with their events,
+// steps and dependency edges, then the blobs of their offloaded payloads.
+// Jobs that are not finished, or belong to another tenant, are skipped; an
+// empty tenantID matches every tenant. It returns the number of jobs deleted.
+func (r *JobRepo) PurgeJobs(ctx context.Context, tenantID string, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var purged []string
+	var refs []*string
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		purged, refs = nil, nil
+
+		args := stringArgs(ids)
+		tenantCond := ""
+		if tenantID != "" {
+			tenantCond = " AND tenant_id = ?"
+			args = append(args, tenantID)
+		}
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, payload_ref
+			FROM jobs
+			WHERE id IN (`+placeholders(len(ids))+`)
+				AND status IN ('SUCCESS', 'FAILED', 'CANCELLED', 'COMPENSATED', 'COMPENSATION_FAILED')`+tenantCond+`
+			FOR UPDATE
+		`, args...)
+		if err != nil {
+			return fmt.Errorf("lock jobs: %w", err)
+		}
+		for rows.Next() {
+			var id string
+			var ref sql.NullString
+			if err := rows.Scan(&id, &ref); err != nil {
+				rows.Close()
+				return err
+			}
+			purged = append(purged, id)
+			if ref.Valid {
+				s := ref.String
+				refs = append(refs, &s)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(purged) == 0 {
+			return nil
+		}
+
+		in := placeholders(len(purged))
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM job_events WHERE job_id IN (`+in+`)
+		`, stringArgs(purged)...); err != nil {
+			return fmt.Errorf("delete events: %w", err)
+		}
+		// job_executions and job_dependencies cascade.
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM jobs WHERE id IN (`+in+`)
+		`, stringArgs(purged)...); err != nil {
+			return fmt.Errorf("delete jobs: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Only once no row can point at them any more.
+	r.payloads.discard(refs...)
+	return len(purged), nil
+}
@@ -0,0 +1,206 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"task-scheduler/internal/domain"
+)
+
+// CreateJobs creates many jobs of one tenant, insertChunkSize at a time,
+// each chunk with a single multi-row INSERT. Items with an idempotency key
+// already in use are replayed or rejected like CreateJob does, and a problem
+// with one item never fails the others. If a chunk cannot be inserted as a
+// whole (a key taken concurrently, or the queued quota reached mid-chunk),
+// its items fall back to CreateJob one by one.
+//
+// Specs must be simple jobs: dependencies and unique keys are rejected.
+func (r *JobRepo) CreateJobs(ctx context.Context, specs []domain.JobSpec) []domain.BulkItemResult {
+	results := make([]domain.BulkItemResult, len(specs))
+	for start := 0; start < len(specs); start += insertChunkSize {
+		end := min(start+insertChunkSize, len(specs))
+		r.createChunk(ctx, specs[start:end], results[start:end])
+	}
+	return results
+}
+
+func (r *JobRepo) createChunk(ctx context.Context, specs []domain.JobSpec, results []domain.BulkItemResult) {
+	// first maps each idempotency key to the first item using it; later
+	// items with the same key replay that item.
+	first := map[string]int{}
+	fingerprints := make([]string, len(specs))
+	var keys []string
+	var fresh []int
+
+	tenantID := ""
+	for i := range specs {
+		s := &specs[i]
+		if err := normalizeBulkSpec(s); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if tenantID == "" {
+			tenantID = s.TenantID
+		} else if s.TenantID != tenantID {
+			results[i].Err = fmt.Errorf("%w: bulk items must share a tenant", domain.ErrInvalidInput)
+			continue
+		}
+
+		if s.IdempotencyKey == nil {
+			fresh = append(fresh, i)
+			continue
+		}
+		fp, err := requestFingerprint(*s)
+		if err != nil {
+			results[i].Err = fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+			continue
+		}
+		fingerprints[i] = fp
+		if _, dup := first[*s.IdempotencyKey]; dup {
+			continue
+		}
+		first[*s.IdempotencyKey] = i
+		keys = append(keys, *s.IdempotencyKey)
+	}
+
+	held, err := r.heldIdempotencyKeys(ctx, tenantID, keys)
+	for key, i := range first {
+		switch h, ok := held[key]; {
+		case err != nil:
+			results[i].Err = err
+		case !ok:
+			fresh = append(fresh, i)
+		case h.fingerprint != fingerprints[i]:
+			results[i] = domain.BulkItemResult{JobID: h.jobID, Err: fmt.Errorf("%w: idempotency key %q was used for a different request", domain.ErrConflict, key)}
+		default:
+			results[i] = domain.BulkItemResult{JobID: h.jobID, Outcome: domain.OutcomeReplayed}
+		}
+	}
+
+	if len(fresh) > 0 {
+		r.insertFresh(ctx, specs, results, fresh)
+	}
+
+	// Repeated keys within the request replay their first item.
+	for i := range specs {
+		s := specs[i]
+		if results[i].Outcome != "" || results[i].Err != nil || s.IdempotencyKey == nil {
+			continue
+		}
+		j := first[*s.IdempotencyKey]
+		if j == i {
+			continue
+		}
+		switch {
+		case results[j].Err != nil:
+			results[i].Err = results[j].Err
+		case fingerprints[j] != fingerprints[i]:
+			results[i] = domain.BulkItemResult{JobID: results[j].JobID, Err: fmt.Errorf("%w: idempotency key %q was used for a different request", domain.ErrConflict, *s.IdempotencyKey)}
+		default:
+			results[i] = domain.BulkItemResult{JobID: results[j].JobID, Outcome: domain.OutcomeReplayed}
+		}
+	}
+}
+
+// insertFresh inserts specs[idx...] with one statement, or one by one with
+// CreateJob if the chunk cannot go in as a whole.
+func (r *JobRepo) insertFresh(ctx context.Context, specs []domain.JobSpec, results []domain.BulkItemResult, idx []int) {
+	batch := make([]domain.JobSpec, len(idx))
+	for n, i := range idx {
+		batch[n] = specs[i]
+	}
+
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		if err := checkQueuedQuota(ctx, tx, batch[0].TenantID, len(batch)); err != nil {
+			return err
+		}
+		return insertJobRows(ctx, tx, r.payloads, batch)
+	})
+	if err == nil {
+		for _, i := range idx {
+			results[i] = domain.BulkItemResult{JobID: specs[i].ID, Outcome: domain.OutcomeCreated}
+		}
+		return
+	}
+
+	if !errors.Is(err, domain.ErrQuotaExceeded) && !isDuplicateKey(err) {
+		for _, i := range idx {
+			results[i].Err = err
+		}
+		return
+	}
+	for _, i := range idx {
+		job, outcome, err := r.CreateJob(ctx, specs[i])
+		if err != nil {
+			results[i].Err = err
+			if job != nil {
+				results[i].JobID = job.ID
+			}
+			continue
+		}
+		results[i] = domain.BulkItemResult{JobID: job.ID, Outcome: outcome}
+	}
+}
+
+type heldKey struct {
+	jobID       string
+	fingerprint string
+}
+
+// heldIdempotencyKeys returns the live holders of keys in tenantID. Expired
+// keys are released on the way, so they count as free.
+func (r *JobRepo) heldIdempotencyKeys(ctx context.Context, tenantID string, keys []string) (map[string]heldKey, error) {
+	held := map[string]heldKey{}
+	if len(keys) == 0 {
+		return held, nil
+	}
+
+	args := append([]any{tenantID}, stringArgs(keys)...)
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, idempotency_key, COALESCE(idempotency_fingerprint, ''),
+			idempotency_expires_at IS NOT NULL AND idempotency_expires_at <= NOW(6)
+		FROM jobs
+		WHERE tenant_id = ? AND idempotency_key IN (`+placeholders(len(keys))+`)
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("load idempotency keys: %w", err)
+	}
+	defer rows.Close()
+
+	type expiredKey struct{ jobID, key string }
+	var expired []expiredKey
+	for rows.Next() {
+		var h heldKey
+		var key string
+		var isExpired bool
+		if err := rows.Scan(&h.jobID, &key, &h.fingerprint, &isExpired); err != nil {
+			return nil, err
+		}
+		if isExpired {
+			expired = append(expired, expiredKey{jobID: h.jobID, key: key})
+			continue
+		}
+		held[key] = h
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, e := range expired {
+		if _, err := r.releaseExpiredIdempotencyKey(ctx, e.jobID, e.key); err != nil {
+			return nil, err
+		}
+	}
+	return held, nil
+}
+
+func normalizeBulkSpec(s *domain.JobSpec) error {
+	if s.Unique != nil || len(s.DependsOn) > 0 {
+		return fmt.Errorf("%w: unique keys and dependencies are not supported in bulk", domain.ErrInvalidInput)
+	}
+	_, _, err := prepareSpec(s)
+	return err
+}
@@ -0,0 +1,150 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+// dueJobPredicate matches jobs ready to be (re)claimed: PENDING and
+// COMPENSATING jobs whose run time has come and that hold no live lease, and
+// RUNNING jobs whose lease expired. Takes now three times.
+const dueJobPredicate = `(
+	(
+		status IN ('PENDING', 'COMPENSATING')
+		AND (next_run_at IS NULL OR next_run_at <= ?)
+		AND (locked_until IS NULL OR locked_until <= ?)
+	)
+	OR
+	(
+		status = 'RUNNING'
+		AND locked_until IS NOT NULL
+		AND locked_until <= ?
+	)
+)`
+
+// maxTenantsPerClaim bounds how many tenants a single claim pass considers.
+const maxTenantsPerClaim = 256
+
+// dueTenants lists tenants that currently have claimable work.
+func dueTenants(ctx context.Context, tx *sql.Tx, now time.Time) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT tenant_id
+		FROM jobs
+		WHERE `+dueJobPredicate+notPausedPredicate+`
+		LIMIT ?
+	`, now, now, now, maxTenantsPerClaim)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tenants []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+	return tenants, rows.Err()
+}
+
+// claimTenantBatch leases up to limit due jobs of one tenant, moving them to
+// RUNNING immediately so later passes in the same transaction skip them.
+// scanned is the number of rows read before the gate was applied; fewer than
+// limit means the tenant has no more eligible work right now.
+func claimTenantBatch(
+	ctx context.Context,
+	tx *sql.Tx,
+	gate *claimGate,
+	tenantID string,
+	limit int,
+	workerID string,
+	leaseUntil time.Time,
+	now time.Time,
+) (ids []string, scanned int, err error) {
+	exclude, excludeArgs := gate.exclusions()
+	args := append([]any{tenantID, now, now, now}, excludeArgs...)
+	args = append(args, limit)
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, type, queue, concurrency_key
+		FROM jobs
+		WHERE tenant_id = ? AND `+dueJobPredicate+notPausedPredicate+exclude+`
+		ORDER BY priority DESC, next_run_at ASC
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var candidates []claimCandidate
+	for rows.Next() {
+		var c claimCandidate
+		var key sql.NullString
+		if err := rows.Scan(&c.id, &c.jobType, &c.queue, &key); err != nil {
+			rows.Close()
+			return nil, 0, err
+		}
+		if key.Valid {
+			c.concurrencyKey = &key.String
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if err := gate.lock(ctx, tx, candidates); err != nil {
+		return nil, 0, err
+	}
+	// Rejected candidates stay locked until the transaction ends, but are
+	// left untouched: they keep their status and attempts.
+	for _, c := range candidates {
+		if gate.admit(c) {
+			ids = append(ids, c.id)
+		}
+	}
+
+	// Update them to RUNNING + lease; compensating jobs keep their status
+	for _, id := range ids {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE jobs
+			SET
+				status = IF(status = 'COMPENSATING', status, 'RUNNING'),
+				locked_by = ?,
+				locked_until = ?,
+				started_at = COALESCE(started_at, ?)
+			WHERE id = ?
+		`, workerID, leaseUntil, now, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		if err := insertEvent(ctx, tx, id, domain.EventClaimed, &workerID); err != nil {
+			return nil, 0, err
+		}
+	}
+	return ids, len(candidates), nil
+}
+
+// placeholders returns "?, ?, ?" for n arguments.
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func stringArgs(vals []string) []any {
+	args := make([]any, len(vals))
+	for i, v := range vals {
+		args[i] = v
+	}
+	return args
+}
@@ -0,0 +1,461 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type claimCandidate struct {
+	id             string
+	jobType        string
+	queue          string
+	concurrencyKey *string
+}
+
+// claimGate decides which due jobs one ClaimJobs call may lease.
+//
+// Limits are read without locks when the claim starts; that snapshot only
+// serves to skip exhausted limits in the candidate scan. Once candidates are
+// picked, lock takes the rows of the limits they fall under (FOR UPDATE)
+// and re-reads them, so claims of unlimited types never wait, and workers
+// claiming the same limited type cannot overspend it. The locks are held
+// until commit.
+type claimGate struct {
+	now     time.Time
+	buckets map[bucketKey]*tokenBucket
+
+	// capped lists the types with a max_running cap (snapshot).
+	capped map[string]bool
+	// typeRoom is the remaining RUNNING capacity of each capped type whose
+	// concurrency_limits row is locked.
+	typeRoom map[string]int
+	// perKey is max_per_key for types that override the default of 1.
+	perKey map[string]int
+	// keyRunning holds RUNNING counts per locked (type, concurrency_key),
+	// including jobs admitted earlier in this claim.
+	keyRunning map[keyRef]int
+}
+
+type keyRef struct {
+	jobType string
+	key     string
+}
+
+// defaultMaxPerKey applies to jobs with a concurrency_key whose type has no
+// max_per_key configured: a concurrency key means "one at a time" unless
+// told otherwise.
+const defaultMaxPerKey = 1
+
+// bucketKey identifies a rate_limits row; queue is "" for type-wide limits.
+type bucketKey struct {
+	jobType string
+	queue   string
+}
+
+type tokenBucket struct {
+	rate       float64
+	burst      float64
+	tokens     float64
+	refilledAt time.Time
+
+	// locked means the row was read FOR UPDATE in this transaction, so the
+	// bucket may be charged; charged means it must be saved.
+	locked  bool
+	charged bool
+}
+
+func loadClaimGate(ctx context.Context, tx *sql.Tx, now time.Time) (*claimGate, error) {
+	g := &claimGate{
+		now:        now,
+		buckets:    map[bucketKey]*tokenBucket{},
+		capped:     map[string]bool{},
+		typeRoom:   map[string]int{},
+		perKey:     map[string]int{},
+		keyRunning: map[keyRef]int{},
+	}
+	if err := g.loadBuckets(ctx, tx); err != nil {
+		return nil, err
+	}
+	if err := g.loadConcurrencyLimits(ctx, tx); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// loadBuckets reads every rate limit without locking it.
+func (g *claimGate) loadBuckets(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT job_type, queue, rate_per_sec, burst, tokens, refilled_at
+		FROM rate_limits
+	`)
+	if err != nil {
+		return fmt.Errorf("load rate limits: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		k, b, err := g.scanBucket(rows)
+		if err != nil {
+			return err
+		}
+		g.buckets[k] = b
+	}
+	return rows.Err()
+}
+
+// lock locks the limits that cs fall under: their rate-limit buckets,
+// type caps and concurrency keys, in that order, each in key order so that
+// concurrent claims take them in the same order. A claim spanning several
+// batches can still lock out of order; InnoDB then aborts one of the
+// claims, which is retried on the next poll.
+func (g *claimGate) lock(ctx context.Context, tx *sql.Tx, cs []claimCandidate) error {
+	if err := g.lockBuckets(ctx, tx, cs); err != nil {
+		return err
+	}
+	if err := g.lockTypes(ctx, tx, cs); err != nil {
+		return err
+	}
+	return g.lockKeys(ctx, tx, cs)
+}
+
+// lockBuckets locks and reloads the buckets that cs draw from. Rate limits
+// created since the snapshot are left for the next claim.
+func (g *claimGate) lockBuckets(ctx context.Context, tx *sql.Tx, cs []claimCandidate) error {
+	need := map[bucketKey]bool{}
+	for _, c := range cs {
+		for _, k := range []bucketKey{{jobType: c.jobType}, {jobType: c.jobType, queue: c.queue}} {
+			if b, ok := g.buckets[k]; ok && !b.locked {
+				need[k] = true
+			}
+		}
+	}
+	if len(need) == 0 {
+		return nil
+	}
+	keys := make([]bucketKey, 0, len(need))
+	for k := range need {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].jobType != keys[j].jobType {
+			return keys[i].jobType < keys[j].jobType
+		}
+		return keys[i].queue < keys[j].queue
+	})
+	args := make([]any, 0, 2*len(keys))
+	for _, k := range keys {
+		args = append(args, k.jobType, k.queue)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT job_type, queue, rate_per_sec, burst, tokens, refilled_at
+		FROM rate_limits
+		WHERE (job_type, queue) IN (`+strings.TrimSuffix(strings.Repeat("(?, ?), ", len(keys)), ", ")+`)
+		ORDER BY job_type, queue
+		FOR UPDATE
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("lock rate limits: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		k, b, err := g.scanBucket(rows)
+		if err != nil {
+			return err
+		}
+		b.locked = true
+		g.buckets[k] = b
+		delete(need, k)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// Deleted since the snapshot.
+	for k := range need {
+		delete(g.buckets, k)
+	}
+	return nil
+}
+
+// scanBucket reads a rate_limits row and refills it for the time elapsed
+// since the last claim.
+func (g *claimGate) scanBucket(rows *sql.Rows) (bucketKey, *tokenBucket, error) {
+	var k bucketKey
+	var b tokenBucket
+	var burst int
+	if err := rows.Scan(&k.jobType, &k.queue, &b.rate, &burst, &b.tokens, &b.refilledAt); err != nil {
+		return k, nil, err
+	}
+	b.burst = float64(burst)
+
+	// Workers' clocks may disagree slightly; never refill for negative time.
+	if elapsed := g.now.Sub(b.refilledAt).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.refilledAt = g.now
+	}
+	return k, &b, nil
+}
+
+// loadConcurrencyLimits reads the concurrency limits without locking them.
+func (g *claimGate) loadConcurrencyLimits(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT job_type, max_running IS NOT NULL, max_per_key
+		FROM concurrency_limits
+	`)
+	if err != nil {
+		return fmt.Errorf("load concurrency limits: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var jobType string
+		var capped bool
+		var maxPerKey sql.NullInt64
+		if err := rows.Scan(&jobType, &capped, &maxPerKey); err != nil {
+			return err
+		}
+		if capped {
+			g.capped[jobType] = true
+		}
+		if maxPerKey.Valid {
+			g.perKey[jobType] = int(maxPerKey.Int64)
+		}
+	}
+	return rows.Err()
+}
+
+// lockTypes locks the concurrency_limits rows of the capped types among cs,
+// which serializes claims of each capped type across workers, and counts
+// their RUNNING leases.
+func (g *claimGate) lockTypes(ctx context.Context, tx *sql.Tx, cs []claimCandidate) error {
+	need := map[string]bool{}
+	for _, c := range cs {
+		if _, locked := g.typeRoom[c.jobType]; g.capped[c.jobType] && !locked {
+			need[c.jobType] = true
+		}
+	}
+	if len(need) == 0 {
+		return nil
+	}
+	types := make([]string, 0, len(need))
+	for t := range need {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT job_type, max_running, max_per_key
+		FROM concurrency_limits
+		WHERE job_type IN (`+placeholders(len(types))+`)
+		ORDER BY job_type
+		FOR UPDATE
+	`, stringArgs(types)...)
+	if err != nil {
+		return fmt.Errorf("lock concurrency limits: %w", err)
+	}
+	var capped []string
+	for rows.Next() {
+		var jobType string
+		var maxRunning, maxPerKey sql.NullInt64
+		if err := rows.Scan(&jobType, &maxRunning, &maxPerKey); err != nil {
+			rows.Close()
+			return err
+		}
+		if maxRunning.Valid {
+			g.typeRoom[jobType] = int(maxRunning.Int64)
+			capped = append(capped, jobType)
+		}
+		if maxPerKey.Valid {
+			g.perKey[jobType] = int(maxPerKey.Int64)
+		} else {
+			delete(g.perKey, jobType)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// Caps removed since the snapshot.
+	for _, t := range types {
+		if _, ok := g.typeRoom[t]; !ok {
+			delete(g.capped, t)
+		}
+	}
+	if len(capped) == 0 {
+		return nil
+	}
+
+	args := append([]any{g.now}, stringArgs(capped)...)
+	rows, err = tx.QueryContext(ctx, `
+		SELECT type, COUNT(*)
+		FROM jobs
+		WHERE status = 'RUNNING' AND locked_until > ?
+			AND type IN (`+placeholders(len(capped))+`)
+		GROUP BY type
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("count running by type: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var jobType string
+		var running int
+		if err := rows.Scan(&jobType, &running); err != nil {
+			return err
+		}
+		g.typeRoom[jobType] -= running
+	}
+	return rows.Err()
+}
+
+// lockKeys locks the concurrency_keys row of each concurrency key among cs,
+// creating it on first use, and counts the key's RUNNING leases. Workers
+// claiming jobs of the same key queue on that row, and each then sees the
+// others' committed claims; the sibling jobs themselves are not locked.
+func (g *claimGate) lockKeys(ctx context.Context, tx *sql.Tx, cs []claimCandidate) error {
+	need := map[keyRef]bool{}
+	for _, c := range cs {
+		if c.concurrencyKey == nil {
+			continue
+		}
+		ref := keyRef{jobType: c.jobType, key: *c.concurrencyKey}
+		if _, locked := g.keyRunning[ref]; !locked {
+			need[ref] = true
+		}
+	}
+	refs := make([]keyRef, 0, len(need))
+	for ref := range need {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].jobType != refs[j].jobType {
+			return refs[i].jobType < refs[j].jobType
+		}
+		return refs[i].key < refs[j].key
+	})
+
+	for _, ref := range refs {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO concurrency_keys (job_type, concurrency_key, locked_at)
+			VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE locked_at = ?
+		`, ref.jobType, ref.key, g.now, g.now); err != nil {
+			return fmt.Errorf("lock concurrency key: %w", err)
+		}
+		var running int
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*)
+			FROM jobs
+			WHERE type = ? AND concurrency_key = ? AND status = 'RUNNING' AND locked_until > ?
+		`, ref.jobType, ref.key, g.now).Scan(&running); err != nil {
+			return fmt.Errorf("count concurrency key: %w", err)
+		}
+		g.keyRunning[ref] = running
+	}
+	return nil
+}
+
+// exclusions returns a WHERE fragment (starting with " AND") that filters out
+// jobs the gate would reject outright, and its arguments.
+func (g *claimGate) exclusions() (string, []any) {
+	var sb strings.Builder
+	var args []any
+	for jobType, room := range g.typeRoom {
+		if room <= 0 {
+			sb.WriteString(" AND type <> ?")
+			args = append(args, jobType)
+		}
+	}
+	// <=> keeps jobs without a concurrency key: NOT (... = NULL) is NULL.
+	for ref, running := range g.keyRunning {
+		if running >= g.maxPerKey(ref.jobType) {
+			sb.WriteString(" AND NOT (type = ? AND concurrency_key <=> ?)")
+			args = append(args, ref.jobType, ref.key)
+		}
+	}
+	for k, b := range g.buckets {
+		if b.tokens >= 1 {
+			continue
+		}
+		if k.queue == "" {
+			sb.WriteString(" AND type <> ?")
+			args = append(args, k.jobType)
+		} else {
+			sb.WriteString(" AND NOT (type = ? AND queue = ?)")
+			args = append(args, k.jobType, k.queue)
+		}
+	}
+	return sb.String(), args
+}
+
+// admit reports whether c may be claimed and, if so, charges it to every
+// limit that applies. lock must have run for c.
+func (g *claimGate) admit(c claimCandidate) bool {
+	buckets := []*tokenBucket{
+		g.buckets[bucketKey{jobType: c.jobType}],
+		g.buckets[bucketKey{jobType: c.jobType, queue: c.queue}],
+	}
+	for _, b := range buckets {
+		if b != nil && b.tokens < 1 {
+			return false
+		}
+	}
+	if room, capped := g.typeRoom[c.jobType]; capped && room <= 0 {
+		return false
+	}
+
+	var ref keyRef
+	if c.concurrencyKey != nil {
+		ref = keyRef{jobType: c.jobType, key: *c.concurrencyKey}
+		if g.keyRunning[ref] >= g.maxPerKey(ref.jobType) {
+			return false
+		}
+	}
+
+	for _, b := range buckets {
+		if b != nil {
+			b.tokens--
+			b.charged = true
+		}
+	}
+	if _, capped := g.typeRoom[c.jobType]; capped {
+		g.typeRoom[c.jobType]--
+	}
+	if c.concurrencyKey != nil {
+		g.keyRunning[ref]++
+	}
+	return true
+}
+
+// maxPerKey is how many jobs of jobType may run at once per concurrency key.
+func (g *claimGate) maxPerKey(jobType string) int {
+	if limit, ok := g.perKey[jobType]; ok {
+		return limit
+	}
+	return defaultMaxPerKey
+}
+
+// save persists the balances of charged buckets. Must run before the claim
+// commits.
+func (g *claimGate) save(ctx context.Context, tx *sql.Tx) error {
+	for k, b := range g.buckets {
+		if !b.charged {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+			UPDATE rate_limits
+			SET tokens = ?, refilled_at = ?
+			WHERE job_type = ? AND queue = ?
+		`, b.tokens, b.refilledAt, k.jobType, k.queue)
+		if err != nil {
+			return fmt.Errorf("save rate limit: %w", err)
+		}
+	}
+	return nil
+}
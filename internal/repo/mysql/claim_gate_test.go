@@ -0,0 +1,94 @@
+package mysqlrepo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestGate() *claimGate {
+	return &claimGate{
+		now:        time.Now(),
+		buckets:    map[bucketKey]*tokenBucket{},
+		capped:     map[string]bool{},
+		typeRoom:   map[string]int{},
+		perKey:     map[string]int{},
+		keyRunning: map[keyRef]int{},
+	}
+}
+
+func keyed(id, jobType, key string) claimCandidate {
+	return claimCandidate{id: id, jobType: jobType, queue: "default", concurrencyKey: &key}
+}
+
+// Several due jobs share a key that is already at its cap: none is admitted,
+// and the key is excluded so the next scan does not return the same rows.
+func TestClaimGateExcludesFullKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		perKey  map[string]int
+		running int
+		want    int // jobs admitted out of five
+	}{
+		{name: "default cap already running", running: 1, want: 0},
+		{name: "default cap free", running: 0, want: 1},
+		{name: "max_per_key 3 with one running", perKey: map[string]int{"sync": 3}, running: 1, want: 2},
+		{name: "max_per_key 2 full", perKey: map[string]int{"sync": 2}, running: 2, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGate()
+			for k, v := range tt.perKey {
+				g.perKey[k] = v
+			}
+			g.keyRunning[keyRef{jobType: "sync", key: "acct-1"}] = tt.running
+
+			admitted := 0
+			for i := 0; i < 5; i++ {
+				if g.admit(keyed(fmt.Sprintf("job-%d", i), "sync", "acct-1")) {
+					admitted++
+				}
+			}
+			if admitted != tt.want {
+				t.Fatalf("admitted %d jobs, want %d", admitted, tt.want)
+			}
+
+			where, args := g.exclusions()
+			if !strings.Contains(where, "concurrency_key <=> ?") || len(args) != 2 || args[0] != "sync" || args[1] != "acct-1" {
+				t.Errorf("exclusions() = %q %v, want the full key excluded", where, args)
+			}
+		})
+	}
+}
+
+func TestClaimGateKeepsKeysWithRoom(t *testing.T) {
+	g := newTestGate()
+	g.perKey["sync"] = 2
+	g.keyRunning[keyRef{jobType: "sync", key: "acct-1"}] = 0
+
+	if !g.admit(keyed("job-1", "sync", "acct-1")) {
+		t.Fatal("first job rejected, want admitted")
+	}
+	if where, args := g.exclusions(); where != "" || len(args) != 0 {
+		t.Errorf("exclusions() = %q %v, want none while the key has room", where, args)
+	}
+	if !g.admit(keyed("job-2", "sync", "acct-1")) {
+		t.Fatal("second job rejected, want admitted")
+	}
+	if where, _ := g.exclusions(); where == "" {
+		t.Error("exclusions() empty, want the key excluded once full")
+	}
+}
+
+func TestClaimGateAdmitsJobsWithoutKey(t *testing.T) {
+	g := newTestGate()
+	g.keyRunning[keyRef{jobType: "sync", key: "acct-1"}] = 1
+
+	for i := 0; i < 3; i++ {
+		c := claimCandidate{id: fmt.Sprintf("job-%d", i), jobType: "sync", queue: "default"}
+		if !g.admit(c) {
+			t.Fatalf("job without key rejected")
+		}
+	}
+}
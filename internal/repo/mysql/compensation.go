@@ -0,0 +1,93 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+// MarkCompensating moves a RUNNING job that failed terminally into the saga
+// rollback phase. The caller keeps its lease and may start compensating at
+// once; if it dies, the job is reclaimed once the lease expires.
+func (r *JobRepo) MarkCompensating(ctx context.Context, jobID string, attempts int, errMsg string) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE jobs
+			SET
+				status = 'COMPENSATING',
+				attempts = ?,
+				error_message = ?,
+				next_run_at = NULL
+			WHERE id = ? AND status = 'RUNNING'
+		`, attempts, errMsg, jobID)
+		if err != nil {
+			return fmt.Errorf("mark compensating update: %w", err)
+		}
+		if aff, _ := res.RowsAffected(); aff == 0 {
+			return fmt.Errorf("mark compensating rejected: job not RUNNING or not found")
+		}
+		return insertEvent(ctx, tx, jobID, domain.EventCompensating, &errMsg)
+	})
+}
+
+// MarkCompensationRetry releases a COMPENSATING job after a compensation
+// failed, to be retried at nextRunAt. error_message keeps the original
+// failure; errMsg goes to the event log.
+func (r *JobRepo) MarkCompensationRetry(ctx context.Context, jobID string, compensationAttempts int, nextRunAt time.Time, errMsg string) error {
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE jobs
+			SET
+				compensation_attempts = ?,
+				next_run_at = ?,
+				locked_by = NULL,
+				locked_until = NULL
+			WHERE id = ? AND status = 'COMPENSATING'
+		`, compensationAttempts, nextRunAt, jobID)
+		if err != nil {
+			return fmt.Errorf("mark compensation retry update: %w", err)
+		}
+		if aff, _ := res.RowsAffected(); aff == 0 {
+			return fmt.Errorf("mark compensation retry rejected: job not COMPENSATING or not found")
+		}
+		return insertEvent(ctx, tx, jobID, domain.EventCompensationRetry, &errMsg)
+	})
+}
+
+// FinishCompensation ends the saga: COMPENSATED when every compensation
+// succeeded, COMPENSATION_FAILED when retries ran out (errMsg says which).
+func (r *JobRepo) FinishCompensation(ctx context.Context, jobID string, compensationAttempts int, failed bool, errMsg *string, completedAt time.Time) error {
+	status, event := domain.StatusCompensated, domain.EventCompensated
+	if failed {
+		status, event = domain.StatusCompensationFailed, domain.EventCompensationFailed
+	}
+
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE jobs
+			SET
+				status = ?,
+				compensation_attempts = ?,
+				completed_at = ?,
+				locked_by = NULL,
+				locked_until = NULL
+			WHERE id = ? AND status = 'COMPENSATING'
+		`, status, compensationAttempts, completedAt, jobID)
+		if err != nil {
+			return fmt.Errorf("finish compensation update: %w", err)
+		}
+		if aff, _ := res.RowsAffected(); aff == 0 {
+			return fmt.Errorf("finish compensation rejected: job not COMPENSATING or not found")
+		}
+		if err := insertEvent(ctx, tx, jobID, event, errMsg); err != nil {
+			return err
+		}
+		if err := enqueueWebhook(ctx, tx, jobID, event); err != nil {
+			return err
+		}
+		return jobFinished(ctx, tx, r.payloads, jobID, false, completedAt)
+	})
+}
@@ -0,0 +1,34 @@
+package mysqlrepo
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"task-scheduler/internal/codec"
+	"task-scheduler/internal/domain"
+	"task-scheduler/internal/keyring"
+)
+
+// encode compresses doc if compression is on and it is large enough.
+func (o PayloadOptions) encode(doc json.RawMessage) (json.RawMessage, string, error) {
+	out, enc, err := codec.Encode(doc, max(o.CompressMin, 0))
+	if err != nil {
+		return nil, "", fmt.Errorf("compress: %w", err)
+	}
+	return out, enc, nil
+}
+
+// decodePayload restores j.Payload if it is compressed and stored inline in
+// plaintext. Sealed and offloaded payloads keep their PayloadEncoding, to
+// be decoded by whoever opens them (domain.Job.PayloadEncoding).
+func decodePayload(j *domain.Job) error {
+	if j.PayloadEncoding == codec.Identity || j.PayloadRef != nil || keyring.Sealed(j.Payload) {
+		return nil
+	}
+	p, err := codec.Decode(j.PayloadEncoding, j.Payload)
+	if err != nil {
+		return fmt.Errorf("job %s: decode payload: %w", j.ID, err)
+	}
+	j.Payload, j.PayloadEncoding = p, codec.Identity
+	return nil
+}
@@ -0,0 +1,96 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+type ConcurrencyLimitRepo struct {
+	db *sql.DB
+}
+
+func NewConcurrencyLimitRepo(db *sql.DB) *ConcurrencyLimitRepo {
+	return &ConcurrencyLimitRepo{db: db}
+}
+
+func (r *ConcurrencyLimitRepo) ListConcurrencyLimits(ctx context.Context) ([]domain.ConcurrencyLimit, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT job_type, max_running, max_per_key, updated_at
+		FROM concurrency_limits
+		ORDER BY job_type
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.ConcurrencyLimit
+	for rows.Next() {
+		var l domain.ConcurrencyLimit
+		var maxRunning, maxPerKey sql.NullInt64
+		if err := rows.Scan(&l.JobType, &maxRunning, &maxPerKey, &l.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if maxRunning.Valid {
+			n := int(maxRunning.Int64)
+			l.MaxRunning = &n
+		}
+		if maxPerKey.Valid {
+			n := int(maxPerKey.Int64)
+			l.MaxPerKey = &n
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+func (r *ConcurrencyLimitRepo) SetConcurrencyLimit(ctx context.Context, l domain.ConcurrencyLimit) error {
+	if l.JobType == "" {
+		return fmt.Errorf("%w: job_type required", domain.ErrInvalidInput)
+	}
+	if (l.MaxRunning != nil && *l.MaxRunning < 0) || (l.MaxPerKey != nil && *l.MaxPerKey < 1) {
+		return fmt.Errorf("%w: max_running must be >= 0 and max_per_key >= 1", domain.ErrInvalidInput)
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO concurrency_limits (job_type, max_running, max_per_key)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			max_running = VALUES(max_running),
+			max_per_key = VALUES(max_per_key)
+	`, l.JobType, l.MaxRunning, l.MaxPerKey)
+	if err != nil {
+		return fmt.Errorf("set concurrency limit: %w", err)
+	}
+	return nil
+}
+
+func (r *ConcurrencyLimitRepo) DeleteConcurrencyLimit(ctx context.Context, jobType string) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM concurrency_limits WHERE job_type = ?
+	`, jobType)
+	if err != nil {
+		return fmt.Errorf("delete concurrency limit: %w", err)
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// PruneConcurrencyKeys deletes up to limit concurrency_keys rows that no
+// claim has locked since before. A key in use again is simply re-created.
+func (r *ConcurrencyLimitRepo) PruneConcurrencyKeys(ctx context.Context, before time.Time, limit int) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM concurrency_keys WHERE locked_at < ? LIMIT ?
+	`, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("prune concurrency keys: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
@@ -0,0 +1,196 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+// lockParents validates spec.DependsOn and returns how many parents have not
+// finished yet. The parent rows stay locked until the transaction ends, so a
+// parent cannot finish between this check and the child's insert.
+func lockParents(ctx context.Context, tx *sql.Tx, spec domain.JobSpec) (int, error) {
+	if len(spec.DependsOn) == 0 {
+		return 0, nil
+	}
+
+	args := append([]any{spec.TenantID}, stringArgs(spec.DependsOn)...)
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, status
+		FROM jobs
+		WHERE tenant_id = ? AND id IN (`+placeholders(len(spec.DependsOn))+`)
+		FOR UPDATE
+	`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("lock parents: %w", err)
+	}
+	defer rows.Close()
+
+	found, pending := 0, 0
+	for rows.Next() {
+		var id string
+		var status domain.JobStatus
+		if err := rows.Scan(&id, &status); err != nil {
+			return 0, err
+		}
+		found++
+		waiting, err := parentWaits(id, status, spec.OnParentFailure)
+		if err != nil {
+			return 0, err
+		}
+		if waiting {
+			pending++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if found != len(spec.DependsOn) {
+		return 0, fmt.Errorf("%w: unknown or duplicate parent in depends_on", domain.ErrInvalidInput)
+	}
+	return pending, nil
+}
+
+// parentWaits reports whether a new child must wait for a parent in
+// status. A parent that finished without succeeding (failed, cancelled or
+// compensated) fails the child unless it runs regardless of its parents.
+func parentWaits(id string, status domain.JobStatus, policy domain.ParentFailurePolicy) (bool, error) {
+	switch {
+	case status == domain.StatusSuccess:
+		return false, nil
+	case status.Terminal():
+		if policy != domain.ParentFailureRun {
+			return false, fmt.Errorf("%w: parent %s is %s", domain.ErrDependencyFailed, id, status)
+		}
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+func insertDependencies(ctx context.Context, tx *sql.Tx, spec domain.JobSpec) error {
+	if len(spec.DependsOn) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, 2*len(spec.DependsOn))
+	values := make([]string, 0, len(spec.DependsOn))
+	for _, parent := range spec.DependsOn {
+		values = append(values, "(?, ?)")
+		args = append(args, spec.ID, parent)
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO job_dependencies (job_id, parent_id)
+		VALUES `+strings.Join(values, ", "), args...)
+	if err != nil {
+		return fmt.Errorf("insert dependencies: %w", err)
+	}
+	return nil
+}
+
+// settleDependents propagates a parent reaching a terminal status to the
+// BLOCKED jobs waiting on it. A success, or a failure under the "run" policy,
+// releases one pending parent and moves the child to PENDING once none are
+// left. A failure under "cascade" cancels the child, and so on down the graph.
+// Must run in the transaction that finished parentID.
+func settleDependents(ctx context.Context, tx *sql.Tx, payloads PayloadOptions, parentID string, succeeded bool, now time.Time) error {
+	type settled struct {
+		id        string
+		succeeded bool
+	}
+	work := []settled{{parentID, succeeded}}
+
+	for len(work) > 0 {
+		parent := work[0]
+		work = work[1:]
+
+		rows, err := tx.QueryContext(ctx, `
+			SELECT j.id, j.on_parent_failure
+			FROM job_dependencies d
+			JOIN jobs j ON j.id = d.job_id
+			WHERE d.parent_id = ? AND j.status = 'BLOCKED'
+			ORDER BY j.id
+			FOR UPDATE
+		`, parent.id)
+		if err != nil {
+			return fmt.Errorf("load dependents: %w", err)
+		}
+		type child struct {
+			id     string
+			policy domain.ParentFailurePolicy
+		}
+		var children []child
+		for rows.Next() {
+			var c child
+			if err := rows.Scan(&c.id, &c.policy); err != nil {
+				rows.Close()
+				return err
+			}
+			children = append(children, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, c := range children {
+			if parent.succeeded || c.policy == domain.ParentFailureRun {
+				if err := releaseParent(ctx, tx, c.id); err != nil {
+					return err
+				}
+				continue
+			}
+
+			msg := fmt.Sprintf("dependency %s did not succeed", parent.id)
+			res, err := tx.ExecContext(ctx, `
+				UPDATE jobs
+				SET status = 'CANCELLED', completed_at = ?, error_message = ?
+				WHERE id = ? AND status = 'BLOCKED'
+			`, now, msg, c.id)
+			if err != nil {
+				return fmt.Errorf("cascade cancel: %w", err)
+			}
+			if aff, _ := res.RowsAffected(); aff == 0 {
+				continue
+			}
+			if err := insertEvent(ctx, tx, c.id, domain.EventCancelled, &msg); err != nil {
+				return err
+			}
+			if err := enqueueWebhook(ctx, tx, c.id, domain.EventCancelled); err != nil {
+				return err
+			}
+			if err := settleBatch(ctx, tx, payloads, c.id, false, now); err != nil {
+				return err
+			}
+			work = append(work, settled{c.id, false})
+		}
+	}
+	return nil
+}
+
+// releaseParent counts one parent of jobID as settled and unblocks the job
+// when it was the last one.
+func releaseParent(ctx context.Context, tx *sql.Tx, jobID string) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE jobs SET pending_parents = pending_parents - 1
+		WHERE id = ? AND status = 'BLOCKED'
+	`, jobID); err != nil {
+		return fmt.Errorf("release parent: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE jobs SET status = 'PENDING', next_run_at = NOW(6)
+		WHERE id = ? AND status = 'BLOCKED' AND pending_parents <= 0
+	`, jobID)
+	if err != nil {
+		return fmt.Errorf("unblock job: %w", err)
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return nil
+	}
+	return insertEvent(ctx, tx, jobID, domain.EventUnblocked, nil)
+}
@@ -0,0 +1,46 @@
+package mysqlrepo
+
+import (
+	"errors"
+	"testing"
+
+	"task-scheduler/internal/domain"
+)
+
+func TestParentWaits(t *testing.T) {
+	tests := []struct {
+		status  domain.JobStatus
+		policy  domain.ParentFailurePolicy
+		wait    bool
+		wantErr bool
+	}{
+		{status: domain.StatusPending, policy: domain.ParentFailureCascade, wait: true},
+		{status: domain.StatusBlocked, policy: domain.ParentFailureCascade, wait: true},
+		{status: domain.StatusRunning, policy: domain.ParentFailureCascade, wait: true},
+		{status: domain.StatusCompensating, policy: domain.ParentFailureCascade, wait: true},
+		{status: domain.StatusCompensating, policy: domain.ParentFailureRun, wait: true},
+		{status: domain.StatusSuccess, policy: domain.ParentFailureCascade},
+		{status: domain.StatusFailed, policy: domain.ParentFailureCascade, wantErr: true},
+		{status: domain.StatusCancelled, policy: domain.ParentFailureCascade, wantErr: true},
+		{status: domain.StatusCompensated, policy: domain.ParentFailureCascade, wantErr: true},
+		{status: domain.StatusCompensationFailed, policy: domain.ParentFailureCascade, wantErr: true},
+		{status: domain.StatusFailed, policy: domain.ParentFailureRun},
+		{status: domain.StatusCompensated, policy: domain.ParentFailureRun},
+		{status: domain.StatusCompensationFailed, policy: domain.ParentFailureRun},
+	}
+	for _, tt := range tests {
+		wait, err := parentWaits("parent-1", tt.status, tt.policy)
+		if tt.wantErr {
+			if !errors.Is(err, domain.ErrDependencyFailed) {
+				t.Errorf("%s/%s: err = %v, want ErrDependencyFailed", tt.status, tt.policy, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s/%s: err = %v, want nil", tt.status, tt.policy, err)
+		}
+		if wait != tt.wait {
+			t.Errorf("%s/%s: waits = %v, want %v", tt.status, tt.policy, wait, tt.wait)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"task-scheduler/internal/blob"
+	"task-scheduler/internal/keyring"
+)
+
+// seal encrypts payload for jobID if a keyring is set.
+func (o PayloadOptions) seal(jobID string, payload json.RawMessage) ([]byte, error) {
+	if o.Keyring == nil {
+		return []byte(payload), nil
+	}
+	sealed, err := o.Keyring.Seal(payload, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("seal payload: %w", err)
+	}
+	return sealed, nil
+}
+
+// RotatePayloads re-encrypts up to limit jobs with IDs after afterID, in ID
+// order: plaintext payloads are sealed and payloads sealed under an older
+// key are rewrapped with k's primary key. It returns the last ID scanned,
+// or "" once there are no more jobs. A payload changed concurrently is left
+// for the next pass. Offloaded payloads are rewritten in the blob store,
+// under the same key.
+func (r *JobRepo) RotatePayloads(ctx context.Context, k *keyring.Keyring, afterID string, limit int) (next string, rotated int, err error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, CAST(payload AS CHAR), payload_ref
+		FROM jobs
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`, afterID, limit)
+	if err != nil {
+		return "", 0, fmt.Errorf("scan payloads: %w", err)
+	}
+	type row struct {
+		id, payload string
+		ref         sql.NullString
+	}
+	var batch []row
+	for rows.Next() {
+		var rw row
+		if err := rows.Scan(&rw.id, &rw.payload, &rw.ref); err != nil {
+			rows.Close()
+			return "", 0, err
+		}
+		batch = append(batch, rw)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", 0, err
+	}
+
+	for _, rw := range batch {
+		if rw.ref.Valid {
+			ok, err := r.rotateBlob(ctx, k, rw.id, rw.ref.String)
+			if err != nil {
+				return "", rotated, fmt.Errorf("job %s: %w", rw.id, err)
+			}
+			if ok {
+				rotated++
+			}
+			continue
+		}
+		out, changed, err := k.Rotate(json.RawMessage(rw.payload), rw.id)
+		if err != nil {
+			return "", rotated, fmt.Errorf("job %s: %w", rw.id, err)
+		}
+		if !changed {
+			continue
+		}
+		res, err := r.db.ExecContext(ctx, `
+			UPDATE jobs SET payload = ? WHERE id = ? AND payload = CAST(? AS JSON)
+		`, []byte(out), rw.id, rw.payload)
+		if err != nil {
+			return "", rotated, fmt.Errorf("job %s: rewrite payload: %w", rw.id, err)
+		}
+		if aff, _ := res.RowsAffected(); aff > 0 {
+			rotated++
+		}
+	}
+
+	if len(batch) < limit {
+		return "", rotated, nil
+	}
+	return batch[len(batch)-1].id, rotated, nil
+}
+
+// rotateBlob applies k.Rotate to the offloaded payload of jobID.
+func (r *JobRepo) rotateBlob(ctx context.Context, k *keyring.Keyring, jobID, ref string) (bool, error) {
+	blobs := r.payloads.Blobs
+	if blobs == nil {
+		return false, fmt.Errorf("payload is offloaded to %s but no blob store is configured", ref)
+	}
+	raw, err := blobs.Get(ctx, ref)
+	if errors.Is(err, blob.ErrNotFound) {
+		// Replaced or purged since the scan.
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("load payload blob: %w", err)
+	}
+	out, changed, err := k.Rotate(raw, jobID)
+	if err != nil || !changed {
+		return false, err
+	}
+	if err := blobs.Put(ctx, ref, out); err != nil {
+		return false, fmt.Errorf("rewrite payload blob: %w", err)
+	}
+	return true, nil
+}
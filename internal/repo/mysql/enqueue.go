@@ -0,0 +1,101 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"task-scheduler/internal/domain"
+)
+
+// EnqueueTx creates spec inside the caller's transaction, with the same
+// validation, quota, uniqueness and dependency handling as CreateJob. The job
+// becomes visible to workers only when tx commits, and disappears if it rolls
+// back. Payloads are stored as payloads says.
+//
+// A live idempotency key held by an identical request returns that job with
+// domain.OutcomeReplayed; a different request gets domain.ErrConflict. The
+// key's row stays locked until tx ends, so concurrent enqueues with the same
+// key serialize behind it.
+func EnqueueTx(ctx context.Context, tx *sql.Tx, payloads PayloadOptions, spec domain.JobSpec) (*domain.Job, domain.CreateOutcome, error) {
+	if tx == nil {
+		return nil, "", fmt.Errorf("%w: transaction required", domain.ErrInvalidInput)
+	}
+	fingerprint, ttlMicros, err := prepareSpec(&spec)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if spec.IdempotencyKey != nil {
+		existing, err := lockIdempotencyKey(ctx, tx, spec.TenantID, *spec.IdempotencyKey)
+		if err != nil {
+			return nil, "", err
+		}
+		if existing != nil {
+			if existing.IdempotencyFingerprint == nil || *existing.IdempotencyFingerprint != *fingerprint {
+				return existing, "", fmt.Errorf("%w: idempotency key %q was used for a different request", domain.ErrConflict, *spec.IdempotencyKey)
+			}
+			return existing, domain.OutcomeReplayed, nil
+		}
+	}
+
+	if spec.Unique != nil {
+		hit, err := acquireUniqueKey(ctx, tx, payloads, spec)
+		if err != nil {
+			return nil, "", err
+		}
+		if hit != nil {
+			job, err := getJobTx(ctx, tx, hit.jobID)
+			return job, hit.outcome, err
+		}
+	}
+	if err := checkQueuedQuota(ctx, tx, spec.TenantID, 1); err != nil {
+		return nil, "", err
+	}
+	if err := insertJob(ctx, tx, payloads, spec, fingerprint, ttlMicros); err != nil {
+		return nil, "", fmt.Errorf("insert job: %w", err)
+	}
+
+	job, err := getJobTx(ctx, tx, spec.ID)
+	return job, domain.OutcomeCreated, err
+}
+
+// lockIdempotencyKey returns the job holding key in tenantID, locked for
+// the rest of tx. An expired key is released and reported as free.
+func lockIdempotencyKey(ctx context.Context, tx *sql.Tx, tenantID, key string) (*domain.Job, error) {
+	job, err := scanJob(tx.QueryRowContext(ctx, `
+		SELECT `+jobColumns+`
+		FROM jobs
+		WHERE tenant_id = ? AND idempotency_key = ?
+		FOR UPDATE
+	`, tenantID, key))
+	if err != nil || job == nil {
+		return nil, err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE jobs
+		SET
+			idempotency_key = NULL,
+			idempotency_fingerprint = NULL,
+			idempotency_expires_at = NULL
+		WHERE id = ?
+			AND idempotency_expires_at IS NOT NULL
+			AND idempotency_expires_at <= NOW(6)
+	`, job.ID)
+	if err != nil {
+		return nil, fmt.Errorf("release idempotency key: %w", err)
+	}
+	if aff, _ := res.RowsAffected(); aff > 0 {
+		return nil, nil
+	}
+	return job, nil
+}
+
+func getJobTx(ctx context.Context, tx *sql.Tx, id string) (*domain.Job, error) {
+	return scanJob(tx.QueryRowContext(ctx, `
+		SELECT `+jobColumns+`
+		FROM jobs
+		WHERE id = ?
+	`, id))
+}
@@ -0,0 +1,155 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"task-scheduler/internal/domain"
+)
+
+// EventRepo reads the job_events log written by JobRepo.
+//
+// AUTO_INCREMENT ids are allocated at insert time, not commit time, so a slow
+// transaction can commit a lower id after a reader has moved past it. Readers
+// therefore page by seq instead: before each read, committed events that have
+// none are numbered after every event numbered so far. An event still in flight
+// is invisible to sequencing and is numbered once it commits, behind the
+// cursor of no reader.
+type EventRepo struct {
+	db *sql.DB
+
+	// SequenceBatch caps how many events one read numbers.
+	SequenceBatch int
+}
+
+func NewEventRepo(db *sql.DB) *EventRepo {
+	return &EventRepo{db: db, SequenceBatch: 1000}
+}
+
+// sequence numbers committed events that have no seq yet, in id order. The
+// event_sequence row lock makes concurrent readers take turns.
+func (r *EventRepo) sequence(ctx context.Context) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var last int64
+	if err := tx.QueryRowContext(ctx, `
+		SELECT last_seq FROM event_sequence WHERE id = 1 FOR UPDATE
+	`).Scan(&last); err != nil {
+		return fmt.Errorf("lock event sequence: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM job_events WHERE seq IS NULL ORDER BY id LIMIT ?
+	`, r.SequenceBatch)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	for _, id := range ids {
+		last++
+		if _, err := tx.ExecContext(ctx, `UPDATE job_events SET seq = ? WHERE id = ?`, last, id); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE event_sequence SET last_seq = ? WHERE id = 1`, last); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *EventRepo) ListEvents(ctx context.Context, afterID int64, filter domain.EventFilter, limit int) ([]domain.JobEvent, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	if filter.TenantID == "" {
+		return nil, fmt.Errorf("%w: tenant required", domain.ErrInvalidInput)
+	}
+
+	if err := r.sequence(ctx); err != nil {
+		return nil, err
+	}
+
+	where := []string{"tenant_id = ?", "seq > ?"}
+	args := []any{filter.TenantID, afterID}
+	if filter.JobID != "" {
+		where = append(where, "job_id = ?")
+		args = append(args, filter.JobID)
+	}
+	if filter.JobType != "" {
+		where = append(where, "job_type = ?")
+		args = append(args, filter.JobType)
+	}
+	if filter.Queue != "" {
+		where = append(where, "queue = ?")
+		args = append(args, filter.Queue)
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT seq, event_type, job_id, tenant_id, job_type, queue, status, attempts, message, created_at
+		FROM job_events
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY seq ASC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []domain.JobEvent
+	for rows.Next() {
+		var e domain.JobEvent
+		var msg sql.NullString
+		if err := rows.Scan(
+			&e.ID, &e.Type, &e.JobID, &e.TenantID, &e.JobType, &e.Queue,
+			&e.Status, &e.Attempts, &msg, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if msg.Valid {
+			s := msg.String
+			e.Message = &s
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (r *EventRepo) LatestEventID(ctx context.Context) (int64, error) {
+	if err := r.sequence(ctx); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT last_seq FROM event_sequence WHERE id = 1
+	`).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return id, err
+}
@@ -0,0 +1,75 @@
+package mysqlrepo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"task-scheduler/internal/domain"
+)
+
+// requestFingerprint hashes the parts of a create request that must match
+// for an idempotent replay. The payload is re-encoded so that whitespace and
+// object key order do not make two equivalent requests look different.
+func requestFingerprint(spec domain.JobSpec) (string, error) {
+	payload, err := canonicalJSON(spec.Payload)
+	if err != nil {
+		return "", fmt.Errorf("canonicalize payload: %w", err)
+	}
+
+	callback := ""
+	if spec.CallbackURL != nil {
+		callback = *spec.CallbackURL
+	}
+
+	parts := [][]byte{
+		[]byte(spec.Type),
+		[]byte(spec.Queue),
+		[]byte(fmt.Sprint(spec.MaxAttempts)),
+		[]byte(callback),
+		payload,
+	}
+	// Optional fields are appended only when set so fingerprints stored before concurrency keys
+	// existed still match.
+	if spec.ConcurrencyKey != nil {
+		parts = append(parts, []byte("concurrency_key"), []byte(*spec.ConcurrencyKey))
+	}
+	if u := spec.Unique; u != nil {
+		parts = append(parts, []byte("unique"), []byte(u.Key), []byte(u.Policy), []byte(fmt.Sprint(u.Window)))
+	}
+	if len(spec.DependsOn) > 0 {
+		parents := append([]string(nil), spec.DependsOn...)
+		sort.Strings(parents)
+		parts = append(parts, []byte("depends_on"), []byte(strings.Join(parents, ",")), []byte(spec.OnParentFailure))
+	}
+	if spec.Priority != 0 {
+		parts = append(parts, []byte("priority"), []byte(fmt.Sprint(spec.Priority)))
+	}
+	if spec.PayloadVersion > 1 {
+		parts = append(parts, []byte("payload_version"), []byte(fmt.Sprint(spec.PayloadVersion)))
+	}
+
+	h := sha256.New()
+	for _, part := range parts {
+		// Length-prefix each part so field boundaries are unambiguous.
+		fmt.Fprintf(h, "%d:", len(part))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func canonicalJSON(raw []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	// encoding/json sorts map keys, which is all the canonicalization we need.
+	return json.Marshal(v)
+}
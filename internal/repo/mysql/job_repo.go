@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math/rand"
 	"strings"
 	"time"
 
@@ -12,11 +13,12 @@ import (
 )
 
 type JobRepo struct {
-	db *sql.DB
+	db       *sql.DB
+	payloads PayloadOptions
 }
 
-func NewJobRepo(db *sql.DB) *JobRepo {
-	return &JobRepo{db: db}
+func NewJobRepo(db *sql.DB, payloads PayloadOptions) *JobRepo {
+	return &JobRepo{db: db, payloads: payloads}
 }
 
 /*
@@ -25,90 +27,442 @@ API METHODS
 ====================================================
 */
 
-func (r *JobRepo) CreateJob(
-	ctx context.Context,
-	id string,
-	jobType string,
-	payload []byte,
-	maxAttempts int,
-	idempotencyKey *string,
-) (*domain.Job, error) {
+func (r *JobRepo) CreateJob(ctx context.Context, spec domain.JobSpec) (*domain.Job, domain.CreateOutcome, error) {
+	fingerprint, ttlMicros, err := prepareSpec(&spec)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// At most two passes: the second runs only after an expired key was released.
+	for pass := 0; pass < 2; pass++ {
+		var hit *uniqueHit
+		err := r.withTx(ctx, func(tx *sql.Tx) error {
+			if spec.Unique != nil {
+				var err error
+				if hit, err = acquireUniqueKey(ctx, tx, r.payloads, spec); err != nil || hit != nil {
+					return err
+				}
+			}
+			if err := checkQueuedQuota(ctx, tx, spec.TenantID, 1); err != nil {
+				return err
+			}
+			return insertJob(ctx, tx, r.payloads, spec, fingerprint, ttlMicros)
+		})
+		if err == nil && hit != nil {
+			r.payloads.discard(hit.replacedBlob)
+			job, err := r.GetJobByID(ctx, spec.TenantID, hit.jobID)
+			return job, hit.outcome, err
+		}
+		if err == nil {
+			job, err := r.GetJobByID(ctx, spec.TenantID, spec.ID)
+			return job, domain.OutcomeCreated, err
+		}
 
-	if id == "" {
-		return nil, fmt.Errorf("id is required")
+		// An identical replay must succeed even when the tenant is now over quota.
+		overQuota := errors.Is(err, domain.ErrQuotaExceeded)
+		if overQuota && spec.IdempotencyKey == nil {
+			return nil, "", err
+		}
+		if spec.IdempotencyKey == nil || !(overQuota || isDuplicateKey(err)) {
+			return nil, "", fmt.Errorf("insert job: %w", err)
+		}
+
+		existing, getErr := r.GetJobByIdempotencyKey(ctx, spec.TenantID, *spec.IdempotencyKey)
+		if getErr != nil {
+			return nil, "", fmt.Errorf("load idempotent job: %w", getErr)
+		}
+		if existing == nil {
+			if overQuota {
+				return nil, "", err
+			}
+			// The duplicate was on something other than the idempotency key.
+			return nil, "", fmt.Errorf("insert job: %w", err)
+		}
+
+		released, relErr := r.releaseExpiredIdempotencyKey(ctx, existing.ID, *spec.IdempotencyKey)
+		if relErr != nil {
+			return nil, "", relErr
+		}
+		if released {
+			continue
+		}
+
+		if existing.IdempotencyFingerprint == nil || *existing.IdempotencyFingerprint != *fingerprint {
+			return existing, "", fmt.Errorf("%w: idempotency key %q was used for a different request", domain.ErrConflict, *spec.IdempotencyKey)
+		}
+		return existing, domain.OutcomeReplayed, nil
+	}
+
+	return nil, "", fmt.Errorf("insert job: idempotency key %q still held", *spec.IdempotencyKey)
+}
+
+// prepareSpec validates spec, fills in defaults and computes the
+// idempotency columns for insertJob.
+func prepareSpec(spec *domain.JobSpec) (fingerprint *string, ttlMicros *int64, err error) {
+	if spec.ID == "" {
+		return nil, nil, fmt.Errorf("id is required")
+	}
+	if spec.Type == "" {
+		return nil, nil, fmt.Errorf("jobType is required")
+	}
+	if len(spec.Payload) == 0 {
+		return nil, nil, fmt.Errorf("payload is required")
 	}
-	if jobType == "" {
-		return nil, fmt.Errorf("jobType is required")
+	if spec.MaxAttempts <= 0 {
+		spec.MaxAttempts = 3
 	}
-	if len(payload) == 0 {
-		return nil, fmt.Errorf("payload is required")
+	if spec.PayloadVersion <= 0 {
+		spec.PayloadVersion = 1
 	}
-	if maxAttempts <= 0 {
-		maxAttempts = 3
+	if spec.Queue == "" {
+		spec.Queue = domain.DefaultQueue
+	}
+	if spec.TenantID == "" {
+		spec.TenantID = domain.DefaultTenant
+	}
+	if spec.Priority < domain.MinPriority || spec.Priority > domain.MaxPriority {
+		return nil, nil, fmt.Errorf("%w: priority out of range", domain.ErrInvalidInput)
 	}
 
-	_, err := r.db.ExecContext(ctx, `
+	if spec.Unique != nil {
+		if spec.Unique.Key == "" {
+			return nil, nil, fmt.Errorf("%w: unique key required", domain.ErrInvalidInput)
+		}
+		if spec.Unique.Policy == "" {
+			spec.Unique.Policy = domain.UniqueReturnExisting
+		}
+	}
+	if spec.OnParentFailure == "" {
+		spec.OnParentFailure = domain.ParentFailureCascade
+	}
+
+	if spec.IdempotencyKey != nil {
+		fp, err := requestFingerprint(*spec)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+		}
+		fingerprint = &fp
+		if spec.IdempotencyTTL > 0 {
+			us := spec.IdempotencyTTL.Microseconds()
+			ttlMicros = &us
+		}
+	}
+
+	return fingerprint, ttlMicros, nil
+}
+
+// insertJob writes spec as a new job, BLOCKED if it still waits for parents,
+// and records its dependencies and creation event. Callers check quotas and
+// uniqueness first.
+func insertJob(ctx context.Context, tx *sql.Tx, payloads PayloadOptions, spec domain.JobSpec, fingerprint *string, ttlMicros *int64) (err error) {
+	pending, err := lockParents(ctx, tx, spec)
+	if err != nil {
+		return err
+	}
+	status := domain.StatusPending
+	if pending > 0 {
+		status = domain.StatusBlocked
+	}
+
+	var uniqueKey *string
+	if spec.Unique != nil {
+		uniqueKey = &spec.Unique.Key
+	}
+	payload, err := payloads.store(ctx, spec.ID, spec.Payload)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			payloads.discard(payload.ref)
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `
 		INSERT INTO jobs (
-			id, type, payload, status,
+			id, tenant_id, type, queue, payload, payload_encoding, payload_ref, payload_version, status, priority,
 			attempts, max_attempts,
 			next_run_at,
-			idempotency_key
+			idempotency_key, idempotency_fingerprint, idempotency_expires_at,
+			callback_url, concurrency_key, unique_key, api_key_id,
+			batch_id, workflow_id, workflow_node, on_parent_failure, pending_parents
 		) VALUES (
-			?, ?, ?, 'PENDING',
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?,
 			0, ?,
-			NOW(6),
-			?
+			COALESCE(?, NOW(6)),
+			?, ?, DATE_ADD(NOW(6), INTERVAL ? MICROSECOND),
+			?, ?, ?, ?,
+			?, ?, ?, ?, ?
 		)
-	`, id, jobType, payload, maxAttempts, idempotencyKey)
-
+	`, spec.ID, spec.TenantID, spec.Type, spec.Queue, payload.data, payload.encoding, payload.ref, spec.PayloadVersion, status, spec.Priority, spec.MaxAttempts, spec.RunAt,
+		spec.IdempotencyKey, fingerprint, ttlMicros,
+		spec.CallbackURL, spec.ConcurrencyKey, uniqueKey, spec.APIKeyID,
+		spec.BatchID, spec.WorkflowID, spec.WorkflowNode, spec.OnParentFailure, pending)
 	if err != nil {
-		if idempotencyKey != nil {
-			existing, getErr := r.GetJobByIdempotencyKey(ctx, *idempotencyKey)
-			if getErr == nil && existing != nil {
-				return existing, nil
-			}
-		}
-		return nil, fmt.Errorf("insert job: %w", err)
+		return err
 	}
+	if err := insertDependencies(ctx, tx, spec); err != nil {
+		return err
+	}
+	return insertEvent(ctx, tx, spec.ID, domain.EventCreated, nil)
+}
 
-	return r.GetJobByID(ctx, id)
+// releaseExpiredIdempotencyKey frees key from jobID if its TTL has passed,
+// so a new request may claim it. The old job keeps running unaffected.
+func (r *JobRepo) releaseExpiredIdempotencyKey(ctx context.Context, jobID, key string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET
+			idempotency_key = NULL,
+			idempotency_fingerprint = NULL,
+			idempotency_expires_at = NULL
+		WHERE id = ?
+			AND idempotency_key = ?
+			AND idempotency_expires_at IS NOT NULL
+			AND idempotency_expires_at <= NOW(6)
+	`, jobID, key)
+	if err != nil {
+		return false, fmt.Errorf("release idempotency key: %w", err)
+	}
+	aff, _ := res.RowsAffected()
+	return aff > 0, nil
 }
 
-func (r *JobRepo) GetJobByID(ctx context.Context, id string) (*domain.Job, error) {
+func (r *JobRepo) GetJobByID(ctx context.Context, tenantID, id string) (*domain.Job, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT
-			id, type, CAST(payload AS CHAR),
-			status, attempts, max_attempts,
-			next_run_at,
-			idempotency_key,
-			started_at, completed_at, error_message,
-			locked_by, locked_until,
-			created_at, updated_at
+		SELECT `+jobColumns+`
 		FROM jobs
-		WHERE id = ?
-	`, id)
+		WHERE id = ? AND tenant_id = ?
+	`, id, tenantID)
 
 	return scanJob(row)
 }
 
-func (r *JobRepo) GetJobByIdempotencyKey(ctx context.Context, key string) (*domain.Job, error) {
+func (r *JobRepo) GetJobByIdempotencyKey(ctx context.Context, tenantID, key string) (*domain.Job, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT
-			id, type, CAST(payload AS CHAR),
-			status, attempts, max_attempts,
-			next_run_at,
-			idempotency_key,
-			started_at, completed_at, error_message,
-			locked_by, locked_until,
-			created_at, updated_at
+		SELECT `+jobColumns+`
 		FROM jobs
-		WHERE idempotency_key = ?
-		LIMIT 1
-	`, key)
+		WHERE tenant_id = ? AND idempotency_key = ?
+	`, tenantID, key)
 
 	return scanJob(row)
 }
 
+func (r *JobRepo) CancelJob(ctx context.Context, tenantID, id string, now time.Time) (*domain.Job, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: id required", domain.ErrInvalidInput)
+	}
+
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE jobs
+			SET
+				status = 'CANCELLED',
+				completed_at = ?,
+				locked_by = NULL,
+				locked_until = NULL
+			WHERE id = ? AND tenant_id = ? AND status IN ('PENDING', 'BLOCKED', 'RUNNING')
+		`, now, id, tenantID)
+		if err != nil {
+			return fmt.Errorf("cancel job update: %w", err)
+		}
+
+		aff, _ := res.RowsAffected()
+		if aff == 0 {
+			var status string
+			err := tx.QueryRowContext(ctx, `
+				SELECT status FROM jobs WHERE id = ? AND tenant_id = ?
+			`, id, tenantID).Scan(&status)
+			if errors.Is(err, sql.ErrNoRows) {
+				return domain.ErrNotFound
+			}
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("%w: job already %s", domain.ErrConflict, status)
+		}
+		if err := insertEvent(ctx, tx, id, domain.EventCancelled, nil); err != nil {
+			return err
+		}
+		if err := enqueueWebhook(ctx, tx, id, domain.EventCancelled); err != nil {
+			return err
+		}
+		return jobFinished(ctx, tx, r.payloads, id, false, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetJobByID(ctx, tenantID, id)
+}
+
+// CountPayloadVersions reports PENDING and BLOCKED jobs by type and payload
+// version; an empty tenantID counts every tenant.
+func (r *JobRepo) CountPayloadVersions(ctx context.Context, tenantID string) ([]domain.PayloadVersionCount, error) {
+	where := "status IN ('PENDING', 'BLOCKED')"
+	var args []any
+	if tenantID != "" {
+		where += " AND tenant_id = ?"
+		args = append(args, tenantID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT type, payload_version, COUNT(*)
+		FROM jobs
+		WHERE `+where+`
+		GROUP BY type, payload_version
+		ORDER BY type, payload_version
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("count payload versions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []domain.PayloadVersionCount
+	for rows.Next() {
+		var c domain.PayloadVersionCount
+		if err := rows.Scan(&c.Type, &c.PayloadVersion, &c.Queued); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// ListJobs returns jobs matching filter, newest first. Pass the last ID of a
+// page as filter.Before to get the next one.
+func (r *JobRepo) ListJobs(ctx context.Context, filter domain.JobFilter, limit int) ([]domain.Job, error) {
+	if filter.TenantID == "" {
+		return nil, fmt.Errorf("%w: tenant required", domain.ErrInvalidInput)
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	where := []string{"tenant_id = ?"}
+	args := []any{filter.TenantID}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, string(filter.Status))
+	}
+	if filter.Type != "" {
+		where = append(where, "type = ?")
+		args = append(args, filter.Type)
+	}
+	if filter.Queue != "" {
+		where = append(where, "queue = ?")
+		args = append(args, filter.Queue)
+	}
+	if filter.Before != "" {
+		where = append(where, "id < ?")
+		args = append(args, filter.Before)
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+jobColumns+`
+		FROM jobs
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var out []domain.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *job)
+	}
+	return out, rows.Err()
+}
+
+// RetryJob moves a FAILED or CANCELLED job back to PENDING with its attempts
+// reset. Jobs whose outcome was already counted elsewhere (batch members,
+// jobs still waiting on parents) cannot be retried, nor can a unique job
+// whose key has since been taken by another live job.
+func (r *JobRepo) RetryJob(ctx context.Context, tenantID, id string, now time.Time) (*domain.Job, error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: id required", domain.ErrInvalidInput)
+	}
+
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		var status domain.JobStatus
+		var jobType string
+		var batchID, uniqueKey sql.NullString
+		var pendingParents int
+		err := tx.QueryRowContext(ctx, `
+			SELECT status, type, batch_id, unique_key, pending_parents
+			FROM jobs
+			WHERE id = ? AND tenant_id = ?
+			FOR UPDATE
+		`, id, tenantID).Scan(&status, &jobType, &batchID, &uniqueKey, &pendingParents)
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case status != domain.StatusFailed && status != domain.StatusCancelled:
+			return fmt.Errorf("%w: job is %s", domain.ErrConflict, status)
+		case batchID.Valid:
+			return fmt.Errorf("%w: batch jobs cannot be retried", domain.ErrConflict)
+		case pendingParents > 0:
+			return fmt.Errorf("%w: job is still waiting on parents", domain.ErrConflict)
+		}
+
+		if uniqueKey.Valid {
+			var other string
+			err := tx.QueryRowContext(ctx, `
+				SELECT id FROM jobs
+				WHERE tenant_id = ? AND type = ? AND unique_key = ? AND id <> ?
+					AND status IN ('PENDING', 'BLOCKED', 'RUNNING')
+				LIMIT 1
+			`, tenantID, jobType, uniqueKey.String, id).Scan(&other)
+			if err == nil {
+				return fmt.Errorf("%w: unique key held by job %s", domain.ErrConflict, other)
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+		if err := checkQueuedQuota(ctx, tx, tenantID, 1); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE jobs
+			SET
+				status = 'PENDING',
+				attempts = 0,
+				compensation_attempts = 0,
+				next_run_at = ?,
+				started_at = NULL,
+				completed_at = NULL,
+				error_message = NULL,
+				locked_by = NULL,
+				locked_until = NULL
+			WHERE id = ?
+		`, now, id)
+		if err != nil {
+			return fmt.Errorf("retry job update: %w", err)
+		}
+		return insertEvent(ctx, tx, id, domain.EventRetried, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetJobByID(ctx, tenantID, id)
+}
+
 /*
 ====================================================
 WORKER METHODS (TEMP STUBS)
@@ -140,72 +494,78 @@ func (r *JobRepo) ClaimJobs(
 	}
 	defer tx.Rollback()
 
-	rows, err := tx.QueryContext(ctx, `
-		SELECT id
-		FROM jobs
-		WHERE
-			(
-				status = 'PENDING'
-				AND (next_run_at IS NULL OR next_run_at <= ?)
-				AND (locked_until IS NULL OR locked_until <= ?)
-			)
-			OR
-			(
-				status = 'RUNNING'
-				AND locked_until IS NOT NULL
-				AND locked_until <= ?
-			)
-		ORDER BY next_run_at ASC
-		LIMIT ?
-		FOR UPDATE SKIP LOCKED
-	`, now, now, now, limit)
+	tenants, err := dueTenants(ctx, tx, now)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var ids []string
-	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
-			return nil, err
-		}
-		ids = append(ids, id)
-	}
-
-	if len(ids) == 0 {
+	if len(tenants) == 0 {
 		tx.Commit()
 		return []domain.Job{}, nil
 	}
 
-	// Update them to RUNNING + lease
-	for _, id := range ids {
-		_, err := tx.ExecContext(ctx, `
-			UPDATE jobs
-			SET
-				status = 'RUNNING',
-				locked_by = ?,
-				locked_until = ?,
-				started_at = COALESCE(started_at, ?)
-			WHERE id = ?
-		`, workerID, leaseUntil, now, id)
-		if err != nil {
-			return nil, err
+	// Locks capped tenants' quota rows, serializing claims for them across workers.
+	capacity, err := tenantCapacity(ctx, tx, tenants, now)
+	if err != nil {
+		return nil, err
+	}
+	// Reads the limits; rate-limit buckets are locked per candidate batch.
+	gate, err := loadClaimGate(ctx, tx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Visit tenants in random order and take an equal share from each per pass,
+	// so a tenant with a deep backlog cannot crowd out the rest.
+	rand.Shuffle(len(tenants), func(i, j int) { tenants[i], tenants[j] = tenants[j], tenants[i] })
+	share := (limit + len(tenants) - 1) / len(tenants)
+	exhausted := map[string]bool{}
+
+	var ids []string
+	for progress := true; progress && len(ids) < limit; {
+		progress = false
+		for _, tenant := range tenants {
+			want := min(share, limit-len(ids))
+			if c, capped := capacity[tenant]; capped {
+				want = min(want, c)
+			}
+			if want <= 0 || exhausted[tenant] {
+				continue
+			}
+
+			_, excluded := gate.exclusions()
+			batch, scanned, err := claimTenantBatch(ctx, tx, gate, tenant, want, workerID, leaseUntil, now)
+			if err != nil {
+				return nil, err
+			}
+			if scanned < want {
+				exhausted[tenant] = true
+			}
+			// Exclusions only grow during a claim. If none were added and
+			// nothing was admitted, the next scan would return the same rows.
+			if _, after := gate.exclusions(); len(batch) == 0 && len(after) == len(excluded) {
+				exhausted[tenant] = true
+			}
+			if _, capped := capacity[tenant]; capped {
+				capacity[tenant] -= len(batch)
+			}
+			// Rejections also count as progress: the gate now excludes those
+			// jobs in SQL, so the next pass can reach rows behind them.
+			if len(batch) > 0 || scanned > len(batch) {
+				progress = true
+				ids = append(ids, batch...)
+			}
 		}
 	}
 
+	if err := gate.save(ctx, tx); err != nil {
+		return nil, err
+	}
+
 	// Fetch full rows
 	var claimed []domain.Job
 	for _, id := range ids {
 		row := tx.QueryRowContext(ctx, `
-			SELECT
-				id, type, CAST(payload AS CHAR),
-				status, attempts, max_attempts,
-				next_run_at,
-				idempotency_key,
-				started_at, completed_at, error_message,
-				locked_by, locked_until,
-				created_at, updated_at
+			SELECT `+jobColumns+`
 			FROM jobs
 			WHERE id = ?
 		`, id)
@@ -226,6 +586,9 @@ func (r *JobRepo) ClaimJobs(
 	return claimed, nil
 }
 
+// Heartbeat extends the lease workerID holds on a RUNNING or COMPENSATING
+// job to now+extendBy. It fails with domain.ErrConflict once the lease is
+// lost: the job finished, or another worker (or the reaper) took it over.
 func (r *JobRepo) Heartbeat(
 	ctx context.Context,
 	jobID string,
@@ -233,7 +596,24 @@ func (r *JobRepo) Heartbeat(
 	extendBy time.Duration,
 	now time.Time,
 ) error {
-	return fmt.Errorf("Heartbeat not implemented yet")
+	if jobID == "" || workerID == "" {
+		return fmt.Errorf("jobID and workerID are required")
+	}
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE jobs
+		SET locked_until = ?
+		WHERE id = ?
+			AND status IN ('RUNNING', 'COMPENSATING')
+			AND locked_by = ?
+	`, now.Add(extendBy), jobID, workerID)
+	if err != nil {
+		return fmt.Errorf("heartbeat update: %w", err)
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return fmt.Errorf("%w: lease on job %s lost", domain.ErrConflict, jobID)
+	}
+	return nil
 }
 
 func (r *JobRepo) MarkSuccess(
@@ -248,25 +628,33 @@ func (r *JobRepo) MarkSuccess(
 		completedAt = time.Now()
 	}
 
-	res, err := r.db.ExecContext(ctx, `
-		UPDATE jobs
-		SET
-			status = 'SUCCESS',
-			completed_at = ?,
-			error_message = NULL,
-			locked_by = NULL,
-			locked_until = NULL
-		WHERE id = ? AND status = 'RUNNING'
-	`, completedAt, jobID)
-	if err != nil {
-		return fmt.Errorf("mark success update: %w", err)
-	}
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE jobs
+			SET
+				status = 'SUCCESS',
+				completed_at = ?,
+				error_message = NULL,
+				locked_by = NULL,
+				locked_until = NULL
+			WHERE id = ? AND status = 'RUNNING'
+		`, completedAt, jobID)
+		if err != nil {
+			return fmt.Errorf("mark success update: %w", err)
+		}
 
-	aff, _ := res.RowsAffected()
-	if aff == 0 {
-		return fmt.Errorf("mark success rejected: job not RUNNING or not found")
-	}
-	return nil
+		aff, _ := res.RowsAffected()
+		if aff == 0 {
+			return fmt.Errorf("mark success rejected: job not RUNNING or not found")
+		}
+		if err := insertEvent(ctx, tx, jobID, domain.EventSucceeded, nil); err != nil {
+			return err
+		}
+		if err := enqueueWebhook(ctx, tx, jobID, domain.EventSucceeded); err != nil {
+			return err
+		}
+		return jobFinished(ctx, tx, r.payloads, jobID, true, completedAt)
+	})
 }
 
 func (r *JobRepo) MarkFailure(
@@ -286,15 +674,17 @@ func (r *JobRepo) MarkFailure(
 	}
 
 	status := "PENDING"
+	event := domain.EventRetryScheduled
 	var comp any = nil
+	doneAt := time.Now()
 	if terminal {
 		status = "FAILED"
+		event = domain.EventFailed
 		// terminal failures should have completed_at
 		if completedAt != nil {
-			comp = *completedAt
-		} else {
-			comp = time.Now()
+			doneAt = *completedAt
 		}
+		comp = doneAt
 	}
 
 	// For retry: keep completed_at NULL and set next_run_at
@@ -303,27 +693,38 @@ func (r *JobRepo) MarkFailure(
 		next = *nextRunAt
 	}
 
-	res, err := r.db.ExecContext(ctx, `
-		UPDATE jobs
-		SET
-			status = ?,
-			attempts = ?,
-			next_run_at = ?,
-			completed_at = ?,
-			error_message = ?,
-			locked_by = NULL,
-			locked_until = NULL
-		WHERE id = ? AND status = 'RUNNING'
-	`, status, attempts, next, comp, errMsg, jobID)
-	if err != nil {
-		return fmt.Errorf("mark failure update: %w", err)
-	}
+	return r.withTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE jobs
+			SET
+				status = ?,
+				attempts = ?,
+				next_run_at = ?,
+				completed_at = ?,
+				error_message = ?,
+				locked_by = NULL,
+				locked_until = NULL
+			WHERE id = ? AND status = 'RUNNING'
+		`, status, attempts, next, comp, errMsg, jobID)
+		if err != nil {
+			return fmt.Errorf("mark failure update: %w", err)
+		}
 
-	aff, _ := res.RowsAffected()
-	if aff == 0 {
-		return fmt.Errorf("mark failure rejected: job not RUNNING or not found")
-	}
-	return nil
+		aff, _ := res.RowsAffected()
+		if aff == 0 {
+			return fmt.Errorf("mark failure rejected: job not RUNNING or not found")
+		}
+		if err := insertEvent(ctx, tx, jobID, event, &errMsg); err != nil {
+			return err
+		}
+		if !terminal {
+			return nil
+		}
+		if err := enqueueWebhook(ctx, tx, jobID, event); err != nil {
+			return err
+		}
+		return jobFinished(ctx, tx, r.payloads, jobID, false, doneAt)
+	})
 }
 
 func (r *JobRepo) RecordStepOnce(
@@ -369,6 +770,59 @@ HELPERS
 ====================================================
 */
 
+const jobColumns = `
+	id, tenant_id, type, queue, CAST(payload AS CHAR), payload_encoding, payload_ref, payload_version,
+	status, priority, attempts, max_attempts,
+	next_run_at, compensation_attempts,
+	idempotency_key, idempotency_fingerprint, idempotency_expires_at,
+	callback_url, concurrency_key, unique_key, api_key_id,
+	batch_id, workflow_id, workflow_node, on_parent_failure,
+	started_at, completed_at, error_message,
+	locked_by, locked_until,
+	created_at, updated_at`
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (r *JobRepo) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertEvent appends a lifecycle event for jobID, copying type/queue/status
+// from the job row as seen inside the caller's transaction.
+func insertEvent(ctx context.Context, ex execer, jobID string, eventType domain.EventType, msg *string) error {
+	_, err := ex.ExecContext(ctx, `
+		INSERT INTO job_events (job_id, tenant_id, job_type, queue, event_type, status, attempts, message)
+		SELECT id, tenant_id, type, queue, ?, status, attempts, ?
+		FROM jobs
+		WHERE id = ?
+	`, string(eventType), msg, jobID)
+	if err != nil {
+		return fmt.Errorf("insert event: %w", err)
+	}
+	return nil
+}
+
+// jobFinished updates everything that waits on jobID reaching a terminal
+// status: its batch counters and its dependents. Must run in the transaction
+// that finished the job.
+func jobFinished(ctx context.Context, tx *sql.Tx, payloads PayloadOptions, jobID string, succeeded bool, now time.Time) error {
+	if err := settleBatch(ctx, tx, payloads, jobID, succeeded, now); err != nil {
+		return err
+	}
+	return settleDependents(ctx, tx, payloads, jobID, succeeded, now)
+}
+
 type jobRow interface {
 	Scan(dest ...any) error
 }
@@ -376,9 +830,19 @@ type jobRow interface {
 func scanJob(row jobRow) (*domain.Job, error) {
 	var j domain.Job
 	var payloadStr string
+	var payloadRef sql.NullString
 
 	var nextRunAt sql.NullTime
 	var idemKey sql.NullString
+	var idemFingerprint sql.NullString
+	var idemExpiresAt sql.NullTime
+	var callbackURL sql.NullString
+	var concurrencyKey sql.NullString
+	var uniqueKey sql.NullString
+	var apiKeyID sql.NullString
+	var batchID sql.NullString
+	var workflowID sql.NullString
+	var workflowNode sql.NullString
 	var startedAt sql.NullTime
 	var completedAt sql.NullTime
 	var errMsg sql.NullString
@@ -386,10 +850,12 @@ func scanJob(row jobRow) (*domain.Job, error) {
 	var lockedUntil sql.NullTime
 
 	err := row.Scan(
-		&j.ID, &j.Type, &payloadStr,
-		&j.Status, &j.Attempts, &j.MaxAttempts,
-		&nextRunAt,
-		&idemKey,
+		&j.ID, &j.TenantID, &j.Type, &j.Queue, &payloadStr, &j.PayloadEncoding, &payloadRef, &j.PayloadVersion,
+		&j.Status, &j.Priority, &j.Attempts, &j.MaxAttempts,
+		&nextRunAt, &j.CompensationAttempts,
+		&idemKey, &idemFingerprint, &idemExpiresAt,
+		&callbackURL, &concurrencyKey, &uniqueKey, &apiKeyID,
+		&batchID, &workflowID, &workflowNode, &j.OnParentFailure,
 		&startedAt, &completedAt, &errMsg,
 		&lockedBy, &lockedUntil,
 		&j.CreatedAt, &j.UpdatedAt,
@@ -402,6 +868,13 @@ func scanJob(row jobRow) (*domain.Job, error) {
 	}
 
 	j.Payload = []byte(payloadStr)
+	if payloadRef.Valid {
+		s := payloadRef.String
+		j.PayloadRef = &s
+	}
+	if err := decodePayload(&j); err != nil {
+		return nil, err
+	}
 
 	if nextRunAt.Valid {
 		t := nextRunAt.Time
@@ -411,6 +884,42 @@ func scanJob(row jobRow) (*domain.Job, error) {
 		s := idemKey.String
 		j.IdempotencyKey = &s
 	}
+	if idemFingerprint.Valid {
+		s := idemFingerprint.String
+		j.IdempotencyFingerprint = &s
+	}
+	if idemExpiresAt.Valid {
+		t := idemExpiresAt.Time
+		j.IdempotencyExpiresAt = &t
+	}
+	if callbackURL.Valid {
+		s := callbackURL.String
+		j.CallbackURL = &s
+	}
+	if concurrencyKey.Valid {
+		s := concurrencyKey.String
+		j.ConcurrencyKey = &s
+	}
+	if uniqueKey.Valid {
+		s := uniqueKey.String
+		j.UniqueKey = &s
+	}
+	if apiKeyID.Valid {
+		s := apiKeyID.String
+		j.APIKeyID = &s
+	}
+	if batchID.Valid {
+		s := batchID.String
+		j.BatchID = &s
+	}
+	if workflowID.Valid {
+		s := workflowID.String
+		j.WorkflowID = &s
+	}
+	if workflowNode.Valid {
+		s := workflowNode.String
+		j.WorkflowNode = &s
+	}
 	if startedAt.Valid {
 		t := startedAt.Time
 		j.StartedAt = &t
@@ -0,0 +1,89 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// LeaseRepo hands out named leases (the leases table), so that a task runs
+// on one process of the cluster at a time. Expiry is judged by the
+// database clock, so workers' clocks need not agree.
+type LeaseRepo struct {
+	db *sql.DB
+}
+
+func NewLeaseRepo(db *sql.DB) *LeaseRepo {
+	return &LeaseRepo{db: db}
+}
+
+// AcquireLease takes name for holder until ttl from now, or extends it if
+// holder already has it. It reports false if another holder's lease is
+// still live.
+func (r *LeaseRepo) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	if name == "" || holder == "" {
+		return false, fmt.Errorf("lease name and holder are required")
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	var live bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT holder, locked_until > NOW(6)
+		FROM leases
+		WHERE name = ?
+		FOR UPDATE
+	`, name).Scan(&current, &live)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO leases (name, holder, locked_until, acquired_at)
+			VALUES (?, ?, DATE_ADD(NOW(6), INTERVAL ? MICROSECOND), NOW(6))
+		`, name, holder, ttl.Microseconds())
+		if isDuplicateKey(err) {
+			// Another process created it first.
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("insert lease: %w", err)
+		}
+	case err != nil:
+		return false, fmt.Errorf("lock lease: %w", err)
+	case current != holder && live:
+		return false, nil
+	default:
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE leases
+			SET
+				acquired_at = IF(holder = ?, acquired_at, NOW(6)),
+				holder = ?,
+				locked_until = DATE_ADD(NOW(6), INTERVAL ? MICROSECOND)
+			WHERE name = ?
+		`, holder, holder, ttl.Microseconds(), name); err != nil {
+			return false, fmt.Errorf("take lease: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseLease gives up name if holder has it, so another process can take
+// it at once instead of waiting for it to expire.
+func (r *LeaseRepo) ReleaseLease(ctx context.Context, name, holder string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE leases SET locked_until = NOW(6) WHERE name = ? AND holder = ?
+	`, name, holder)
+	if err != nil {
+		return fmt.Errorf("release lease: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package mysqlrepo
+
+import (
+	"task-scheduler/internal/blob"
+	"task-scheduler/internal/keyring"
+)
+
+// PayloadOptions sets how the repositories that write jobs store payloads.
+// The zero value stores them inline, uncompressed and in plaintext. Rows are
+// decoded as they are read whatever the options, so options can change
+// between runs.
+type PayloadOptions struct {
+	// CompressMin is the size from which payloads and step outputs are
+	// stored gzip-compressed (see package codec), when that makes them
+	// smaller, with the encoding in jobs.payload_encoding and
+	// job_executions.output_encoding; 0 turns compression off. Payloads are
+	// compressed before they are sealed and offloaded.
+	CompressMin int
+
+	// Keyring, if set, seals payloads with its primary key as they are
+	// written, with the job ID as additional data. Reads return the sealed
+	// payload as stored; only the worker, and the API for callers allowed to
+	// see payloads, open it.
+	Keyring *keyring.Keyring
+
+	// Blobs holds offloaded payloads: those larger than BlobThreshold bytes
+	// as stored (that is, after compression and sealing) are written to it
+	// under "jobs/<id>/<random>" and the row keeps only that key in
+	// payload_ref, with a JSON null payload. Workers load the blob when they
+	// run the job; PurgeJobs deletes it. A threshold of 0 offloads nothing
+	// but still lets existing blobs be read, rotated and deleted.
+	Blobs         blob.Store
+	BlobThreshold int
+}
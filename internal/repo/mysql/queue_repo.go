@@ -0,0 +1,115 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+// notPausedPredicate excludes jobs in paused queues from claims.
+const notPausedPredicate = ` AND NOT EXISTS (
+	SELECT 1 FROM paused_queues pq
+	WHERE pq.tenant_id = jobs.tenant_id AND pq.queue = jobs.queue
+)`
+
+type QueueRepo struct {
+	db *sql.DB
+}
+
+func NewQueueRepo(db *sql.DB) *QueueRepo {
+	return &QueueRepo{db: db}
+}
+
+// PauseQueue stops claims from tenantID's queue. Pausing twice is a no-op.
+func (r *QueueRepo) PauseQueue(ctx context.Context, tenantID, queue string) error {
+	if tenantID == "" || queue == "" {
+		return fmt.Errorf("%w: tenant and queue required", domain.ErrInvalidInput)
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT IGNORE INTO paused_queues (tenant_id, queue) VALUES (?, ?)
+	`, tenantID, queue)
+	if err != nil {
+		return fmt.Errorf("pause queue: %w", err)
+	}
+	return nil
+}
+
+// ResumeQueue lets workers claim from the queue again.
+func (r *QueueRepo) ResumeQueue(ctx context.Context, tenantID, queue string) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM paused_queues WHERE tenant_id = ? AND queue = ?
+	`, tenantID, queue)
+	if err != nil {
+		return fmt.Errorf("resume queue: %w", err)
+	}
+	return nil
+}
+
+// ListQueues returns job counts by status for every queue of tenantID that
+// has jobs or is paused, sorted by name.
+func (r *QueueRepo) ListQueues(ctx context.Context, tenantID string) ([]domain.QueueStats, error) {
+	byName := map[string]*domain.QueueStats{}
+	stats := func(queue string) *domain.QueueStats {
+		s, ok := byName[queue]
+		if !ok {
+			s = &domain.QueueStats{Queue: queue, Counts: map[domain.JobStatus]int{}}
+			byName[queue] = s
+		}
+		return s
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT queue, status, COUNT(*)
+		FROM jobs
+		WHERE tenant_id = ?
+		GROUP BY queue, status
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("count jobs: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var queue string
+		var status domain.JobStatus
+		var n int
+		if err := rows.Scan(&queue, &status, &n); err != nil {
+			return nil, err
+		}
+		stats(queue).Counts[status] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	paused, err := r.db.QueryContext(ctx, `
+		SELECT queue, paused_at FROM paused_queues WHERE tenant_id = ?
+	`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("list paused queues: %w", err)
+	}
+	defer paused.Close()
+	for paused.Next() {
+		var queue string
+		var at time.Time
+		if err := paused.Scan(&queue, &at); err != nil {
+			return nil, err
+		}
+		s := stats(queue)
+		s.Paused = true
+		s.PausedAt = &at
+	}
+	if err := paused.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.QueueStats, 0, len(byName))
+	for _, s := range byName {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Queue < out[j].Queue })
+	return out, nil
+}
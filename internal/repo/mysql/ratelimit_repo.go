@@ -0,0 +1,75 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"task-scheduler/internal/domain"
+)
+
+type RateLimitRepo struct {
+	db *sql.DB
+}
+
+func NewRateLimitRepo(db *sql.DB) *RateLimitRepo {
+	return &RateLimitRepo{db: db}
+}
+
+func (r *RateLimitRepo) ListRateLimits(ctx context.Context) ([]domain.RateLimit, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT job_type, queue, rate_per_sec, burst, tokens, updated_at
+		FROM rate_limits
+		ORDER BY job_type, queue
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.RateLimit
+	for rows.Next() {
+		var rl domain.RateLimit
+		if err := rows.Scan(&rl.JobType, &rl.Queue, &rl.RatePerSec, &rl.Burst, &rl.Tokens, &rl.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rl)
+	}
+	return out, rows.Err()
+}
+
+func (r *RateLimitRepo) SetRateLimit(ctx context.Context, rl domain.RateLimit) error {
+	if rl.JobType == "" {
+		return fmt.Errorf("%w: job_type required", domain.ErrInvalidInput)
+	}
+	if rl.RatePerSec <= 0 || rl.Burst < 1 {
+		return fmt.Errorf("%w: rate_per_sec must be > 0 and burst >= 1", domain.ErrInvalidInput)
+	}
+
+	// Shrinking burst clamps the current balance; growing it does not mint tokens.
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO rate_limits (job_type, queue, rate_per_sec, burst, tokens, refilled_at)
+		VALUES (?, ?, ?, ?, ?, NOW(6))
+		ON DUPLICATE KEY UPDATE
+			rate_per_sec = VALUES(rate_per_sec),
+			burst = VALUES(burst),
+			tokens = LEAST(tokens, VALUES(burst))
+	`, rl.JobType, rl.Queue, rl.RatePerSec, rl.Burst, float64(rl.Burst))
+	if err != nil {
+		return fmt.Errorf("set rate limit: %w", err)
+	}
+	return nil
+}
+
+func (r *RateLimitRepo) DeleteRateLimit(ctx context.Context, jobType, queue string) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM rate_limits WHERE job_type = ? AND queue = ?
+	`, jobType, queue)
+	if err != nil {
+		return fmt.Errorf("delete rate limit: %w", err)
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
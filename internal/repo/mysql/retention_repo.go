@@ -0,0 +1,190 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-scheduler/internal/codec"
+	"task-scheduler/internal/domain"
+)
+
+// RetentionRepo finds jobs that have outlived their retention rule and
+// records retention sweeps. Deleting them is JobRepo.PurgeJobs.
+type RetentionRepo struct {
+	db       *sql.DB
+	payloads PayloadOptions
+}
+
+// NewRetentionRepo returns a RetentionRepo that reads offloaded payloads for
+// archives from payloads.Blobs.
+func NewRetentionRepo(db *sql.DB, payloads PayloadOptions) *RetentionRepo {
+	return &RetentionRepo{db: db, payloads: payloads}
+}
+
+// ExpiredJobs returns up to limit IDs of jobs in rule.Status that completed
+// before cutoff, oldest first. With an empty rule.Type, jobs of the types in
+// except (those with rules of their own) are left out.
+func (r *RetentionRepo) ExpiredJobs(ctx context.Context, rule domain.RetentionRule, except []string, cutoff time.Time, limit int) ([]string, error) {
+	where := "status = ? AND completed_at < ?"
+	args := []any{rule.Status, cutoff}
+	switch {
+	case rule.Type != "":
+		where += " AND type = ?"
+		args = append(args, rule.Type)
+	case len(except) > 0:
+		where += " AND type NOT IN (" + placeholders(len(except)) + ")"
+		args = append(args, stringArgs(except)...)
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id
+		FROM jobs
+		WHERE `+where+`
+		ORDER BY completed_at
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("find expired jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ArchiveRecords loads the jobs ids with their execution records, in the
+// form they are stored. Offloaded payloads are read from the blob store.
+func (r *RetentionRepo) ArchiveRecords(ctx context.Context, ids []string) ([]domain.ArchivedJob, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+jobColumns+`
+		FROM jobs
+		WHERE id IN (`+placeholders(len(ids))+`)
+		ORDER BY id
+	`, stringArgs(ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("load jobs: %w", err)
+	}
+	var out []domain.ArchivedJob
+	byID := map[string]int{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byID[j.ID] = len(out)
+		out = append(out, domain.ArchivedJob{Job: *j})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range out {
+		a := &out[i]
+		if a.PayloadRef != nil {
+			if a.Payload, err = r.payloads.load(ctx, *a.PayloadRef); err != nil {
+				return nil, fmt.Errorf("job %s: %w", a.ID, err)
+			}
+		}
+		if a.PayloadEncoding != codec.Identity {
+			a.StoredEncoding = a.PayloadEncoding
+		}
+	}
+
+	rows, err = r.db.QueryContext(ctx, `
+		SELECT job_id, step_key, result_hash, CAST(output AS CHAR), output_encoding, executed_at
+		FROM job_executions
+		WHERE job_id IN (`+placeholders(len(ids))+`)
+		ORDER BY seq
+	`, stringArgs(ids)...)
+	if err != nil {
+		return nil, fmt.Errorf("load executions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var jobID, encoding string
+		var output *string
+		var e domain.ArchivedExecution
+		if err := rows.Scan(&jobID, &e.StepKey, &e.ResultHash, &output, &encoding, &e.ExecutedAt); err != nil {
+			return nil, err
+		}
+		if output != nil {
+			if e.Output, err = decodeOutput(output, encoding); err != nil {
+				return nil, fmt.Errorf("job %s: %w", jobID, err)
+			}
+		}
+		if i, ok := byID[jobID]; ok {
+			out[i].Executions = append(out[i].Executions, e)
+		}
+	}
+	return out, rows.Err()
+}
+
+// SaveRetentionRun records a sweep. Runs older than 90 days are dropped.
+func (r *RetentionRepo) SaveRetentionRun(ctx context.Context, run domain.RetentionRun) error {
+	rules, err := json.Marshal(run.Rules)
+	if err != nil {
+		return err
+	}
+	var archiveFile, errMsg *string
+	if run.ArchiveFile != "" {
+		archiveFile = &run.ArchiveFile
+	}
+	if run.Error != "" {
+		errMsg = &run.Error
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO retention_runs (worker_id, started_at, finished_at, deleted, archived, archive_file, rules, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.WorkerID, run.StartedAt, run.FinishedAt, run.Deleted, run.Archived, archiveFile, rules, errMsg)
+	if err != nil {
+		return fmt.Errorf("save retention run: %w", err)
+	}
+	// The history is only kept for troubleshooting.
+	if _, err := r.db.ExecContext(ctx, `
+		DELETE FROM retention_runs WHERE started_at < DATE_SUB(NOW(6), INTERVAL 90 DAY)
+	`); err != nil {
+		return fmt.Errorf("prune retention runs: %w", err)
+	}
+	return nil
+}
+
+// LastRetentionRun returns the latest sweep, or nil if none has run.
+func (r *RetentionRepo) LastRetentionRun(ctx context.Context) (*domain.RetentionRun, error) {
+	var run domain.RetentionRun
+	var archiveFile, errMsg sql.NullString
+	var rules string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT worker_id, started_at, finished_at, deleted, archived, archive_file, CAST(rules AS CHAR), error
+		FROM retention_runs
+		ORDER BY id DESC
+		LIMIT 1
+	`).Scan(&run.WorkerID, &run.StartedAt, &run.FinishedAt, &run.Deleted, &run.Archived, &archiveFile, &rules, &errMsg)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load retention run: %w", err)
+	}
+	if err := json.Unmarshal([]byte(rules), &run.Rules); err != nil {
+		return nil, fmt.Errorf("load retention run: %w", err)
+	}
+	run.ArchiveFile, run.Error = archiveFile.String, errMsg.String
+	return &run, nil
+}
@@ -0,0 +1,98 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+type PayloadSchemaRepo struct {
+	db *sql.DB
+}
+
+func NewPayloadSchemaRepo(db *sql.DB) *PayloadSchemaRepo {
+	return &PayloadSchemaRepo{db: db}
+}
+
+func (r *PayloadSchemaRepo) ListPayloadSchemas(ctx context.Context) ([]domain.PayloadSchema, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT job_type, CAST(schema_doc AS CHAR), updated_at
+		FROM job_schemas
+		ORDER BY job_type
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.PayloadSchema
+	for rows.Next() {
+		s, err := scanPayloadSchema(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+func (r *PayloadSchemaRepo) GetPayloadSchema(ctx context.Context, jobType string) (*domain.PayloadSchema, error) {
+	s, err := scanPayloadSchema(r.db.QueryRowContext(ctx, `
+		SELECT job_type, CAST(schema_doc AS CHAR), updated_at
+		FROM job_schemas
+		WHERE job_type = ?
+	`, jobType))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return s, err
+}
+
+func (r *PayloadSchemaRepo) SetPayloadSchema(ctx context.Context, s domain.PayloadSchema) error {
+	if s.JobType == "" || len(s.Schema) == 0 {
+		return fmt.Errorf("%w: job_type and schema required", domain.ErrInvalidInput)
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO job_schemas (job_type, schema_doc)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE schema_doc = VALUES(schema_doc)
+	`, s.JobType, []byte(s.Schema))
+	if err != nil {
+		return fmt.Errorf("set payload schema: %w", err)
+	}
+	return nil
+}
+
+func (r *PayloadSchemaRepo) DeletePayloadSchema(ctx context.Context, jobType string) error {
+	res, err := r.db.ExecContext(ctx, `
+		DELETE FROM job_schemas WHERE job_type = ?
+	`, jobType)
+	if err != nil {
+		return fmt.Errorf("delete payload schema: %w", err)
+	}
+	if aff, _ := res.RowsAffected(); aff == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func scanPayloadSchema(row jobRow) (*domain.PayloadSchema, error) {
+	var (
+		s         domain.PayloadSchema
+		doc       string
+		updatedAt time.Time
+	)
+	if err := row.Scan(&s.JobType, &doc, &updatedAt); err != nil {
+		return nil, err
+	}
+	s.Schema = json.RawMessage(doc)
+	s.Source = domain.SchemaSourceAPI
+	s.UpdatedAt = &updatedAt
+	return &s, nil
+}
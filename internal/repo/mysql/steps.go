@@ -0,0 +1,103 @@
+package mysqlrepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"task-scheduler/internal/codec"
+	"task-scheduler/internal/domain"
+)
+
+// stepKeyPrefix namespaces handler steps in job_executions, apart from the
+// runner's own markers such as "execute_success". A step's compensation is
+// recorded under compensationKeyPrefix and the same name.
+const (
+	stepKeyPrefix         = "step:"
+	compensationKeyPrefix = "compensate:"
+)
+
+// LoadSteps returns jobID's completed steps in completion order.
+func (r *JobRepo) LoadSteps(ctx context.Context, jobID string) ([]domain.StepRecord, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.step_key, CAST(s.output AS CHAR), s.output_encoding, c.job_id IS NOT NULL
+		FROM job_executions s
+		LEFT JOIN job_executions c
+			ON c.job_id = s.job_id
+			AND c.step_key = CONCAT(?, SUBSTRING(s.step_key, ?))
+		WHERE s.job_id = ? AND s.step_key LIKE 'step:%'
+		ORDER BY s.seq
+	`, compensationKeyPrefix, len(stepKeyPrefix)+1, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("load steps: %w", err)
+	}
+	defer rows.Close()
+
+	var steps []domain.StepRecord
+	for rows.Next() {
+		var key, encoding string
+		var output *string
+		var st domain.StepRecord
+		if err := rows.Scan(&key, &output, &encoding, &st.Compensated); err != nil {
+			return nil, err
+		}
+		st.Name = strings.TrimPrefix(key, stepKeyPrefix)
+		if st.Output, err = decodeOutput(output, encoding); err != nil {
+			return nil, fmt.Errorf("step %s: %w", st.Name, err)
+		}
+		steps = append(steps, st)
+	}
+	return steps, rows.Err()
+}
+
+// SaveStep records a completed step with its output. Steps are write-once:
+// if another run of the job saved the step first, its output is returned
+// instead and the caller should continue with that.
+func (r *JobRepo) SaveStep(ctx context.Context, jobID, step string, output json.RawMessage) (json.RawMessage, error) {
+	if jobID == "" || step == "" {
+		return nil, fmt.Errorf("jobID and step are required")
+	}
+	key := stepKeyPrefix + step
+	stored, encoding, err := r.payloads.encode(output)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO job_executions (job_id, step_key, output, output_encoding)
+		VALUES (?, ?, ?, ?)
+	`, jobID, key, []byte(stored), encoding)
+	if err == nil {
+		return output, nil
+	}
+	if !isDuplicateKey(err) {
+		return nil, fmt.Errorf("save step: %w", err)
+	}
+
+	var saved *string
+	if err := r.db.QueryRowContext(ctx, `
+		SELECT CAST(output AS CHAR), output_encoding FROM job_executions WHERE job_id = ? AND step_key = ?
+	`, jobID, key).Scan(&saved, &encoding); err != nil {
+		return nil, fmt.Errorf("load saved step: %w", err)
+	}
+	return decodeOutput(saved, encoding)
+}
+
+// decodeOutput restores a step output as read from job_executions.
+func decodeOutput(output *string, encoding string) (json.RawMessage, error) {
+	if output == nil {
+		return json.RawMessage("null"), nil
+	}
+	out, err := codec.Decode(encoding, json.RawMessage(*output))
+	if err != nil {
+		return nil, fmt.Errorf("decode step output: %w", err)
+	}
+	return out, nil
+}
+
+// RecordCompensation marks step's compensation as done for jobID.
+func (r *JobRepo) RecordCompensation(ctx context.Context, jobID, step string) error {
+	_, err := r.RecordStepOnce(ctx, jobID, compensationKeyPrefix+step, nil)
+	return err
+}
@@ -0,0 +1,84 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+// ClaimStuckJobs takes over up to limit RUNNING jobs whose lease ended
+// before cutoff: their worker died or hung. They stay RUNNING but are
+// leased to workerID until leaseUntil, so that the caller can record the
+// lost attempt with MarkFailure or MarkCompensating.
+func (r *JobRepo) ClaimStuckJobs(ctx context.Context, workerID string, cutoff, leaseUntil time.Time, limit int) ([]domain.Job, error) {
+	if workerID == "" {
+		return nil, fmt.Errorf("workerID required")
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var jobs []domain.Job
+	err := r.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id
+			FROM jobs
+			WHERE status = 'RUNNING' AND locked_until IS NOT NULL AND locked_until <= ?
+			ORDER BY locked_until
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		`, cutoff, limit)
+		if err != nil {
+			return fmt.Errorf("find stuck jobs: %w", err)
+		}
+		var ids []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE jobs
+			SET locked_by = ?, locked_until = ?
+			WHERE id IN (`+placeholders(len(ids))+`)
+		`, append([]any{workerID, leaseUntil}, stringArgs(ids)...)...); err != nil {
+			return fmt.Errorf("take over stuck jobs: %w", err)
+		}
+
+		rows, err = tx.QueryContext(ctx, `
+			SELECT `+jobColumns+`
+			FROM jobs
+			WHERE id IN (`+placeholders(len(ids))+`)
+		`, stringArgs(ids)...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			j, err := scanJob(rows)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, *j)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
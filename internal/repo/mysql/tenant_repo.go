@@ -0,0 +1,178 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+type TenantRepo struct {
+	db *sql.DB
+}
+
+func NewTenantRepo(db *sql.DB) *TenantRepo {
+	return &TenantRepo{db: db}
+}
+
+func (r *TenantRepo) GetQuota(ctx context.Context, tenantID string) (*domain.TenantQuota, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT tenant_id, max_running, max_queued, updated_at
+		FROM tenant_quotas
+		WHERE tenant_id = ?
+	`, tenantID)
+
+	q, err := scanQuota(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return q, err
+}
+
+func (r *TenantRepo) SetQuota(ctx context.Context, q domain.TenantQuota) error {
+	if q.TenantID == "" {
+		return fmt.Errorf("%w: tenant_id required", domain.ErrInvalidInput)
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO tenant_quotas (tenant_id, max_running, max_queued)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			max_running = VALUES(max_running),
+			max_queued = VALUES(max_queued)
+	`, q.TenantID, q.MaxRunning, q.MaxQueued)
+	if err != nil {
+		return fmt.Errorf("set quota: %w", err)
+	}
+	return nil
+}
+
+func (r *TenantRepo) ListQuotas(ctx context.Context) ([]domain.TenantQuota, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT tenant_id, max_running, max_queued, updated_at
+		FROM tenant_quotas
+		ORDER BY tenant_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []domain.TenantQuota
+	for rows.Next() {
+		q, err := scanQuota(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *q)
+	}
+	return out, rows.Err()
+}
+
+func scanQuota(row jobRow) (*domain.TenantQuota, error) {
+	var q domain.TenantQuota
+	var maxRunning, maxQueued sql.NullInt64
+	if err := row.Scan(&q.TenantID, &maxRunning, &maxQueued, &q.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if maxRunning.Valid {
+		n := int(maxRunning.Int64)
+		q.MaxRunning = &n
+	}
+	if maxQueued.Valid {
+		n := int(maxQueued.Int64)
+		q.MaxQueued = &n
+	}
+	return &q, nil
+}
+
+// checkQueuedQuota rejects n new jobs if they would take the tenant past
+// max_queued PENDING or BLOCKED jobs. The quota row is locked so concurrent
+// creates cannot overshoot.
+func checkQueuedQuota(ctx context.Context, tx *sql.Tx, tenantID string, n int) error {
+	var maxQueued sql.NullInt64
+	err := tx.QueryRowContext(ctx, `
+		SELECT max_queued FROM tenant_quotas WHERE tenant_id = ? FOR UPDATE
+	`, tenantID).Scan(&maxQueued)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !maxQueued.Valid) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load quota: %w", err)
+	}
+
+	var queued int64
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM jobs WHERE tenant_id = ? AND status IN ('PENDING', 'BLOCKED')
+	`, tenantID).Scan(&queued); err != nil {
+		return fmt.Errorf("count queued: %w", err)
+	}
+	if queued+int64(n) > maxQueued.Int64 {
+		return fmt.Errorf("%w: tenant %q has %d queued jobs (max %d)", domain.ErrQuotaExceeded, tenantID, queued, maxQueued.Int64)
+	}
+	return nil
+}
+
+// tenantCapacity returns how many more jobs each capped tenant may run.
+// Tenants without a max_running quota are absent from the map. The quota rows
+// stay locked until the claim transaction ends.
+func tenantCapacity(ctx context.Context, tx *sql.Tx, tenants []string, now time.Time) (map[string]int, error) {
+	capacity := map[string]int{}
+	if len(tenants) == 0 {
+		return capacity, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT tenant_id, max_running
+		FROM tenant_quotas
+		WHERE max_running IS NOT NULL AND tenant_id IN (`+placeholders(len(tenants))+`)
+		ORDER BY tenant_id
+		FOR UPDATE
+	`, stringArgs(tenants)...)
+	if err != nil {
+		return nil, err
+	}
+	var capped []string
+	for rows.Next() {
+		var t string
+		var max int
+		if err := rows.Scan(&t, &max); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		capacity[t] = max
+		capped = append(capped, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(capped) == 0 {
+		return capacity, nil
+	}
+
+	args := append([]any{now}, stringArgs(capped)...)
+	rows, err = tx.QueryContext(ctx, `
+		SELECT tenant_id, COUNT(*)
+		FROM jobs
+		WHERE status = 'RUNNING' AND locked_until > ?
+			AND tenant_id IN (`+placeholders(len(capped))+`)
+		GROUP BY tenant_id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t string
+		var running int
+		if err := rows.Scan(&t, &running); err != nil {
+			return nil, err
+		}
+		capacity[t] -= running
+	}
+	return capacity, rows.Err()
+}
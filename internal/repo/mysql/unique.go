@@ -0,0 +1,120 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"task-scheduler/internal/domain"
+)
+
+// uniqueHit is an existing job that blocked a create under spec.Unique.
+type uniqueHit struct {
+	jobID   string
+	outcome domain.CreateOutcome
+	// replacedBlob is the blob of the payload a replace overwrote; it may be
+	// deleted once the transaction has committed.
+	replacedBlob *string
+}
+
+// acquireUniqueKey takes the job_unique_keys row for spec's unique key and
+// decides whether the job it points at still blocks spec. If it does, the
+// policy is applied and the hit is returned. Otherwise the row is pointed at
+// spec.ID, which the caller must insert in the same transaction.
+//
+// The row lock serializes concurrent creates of the same key, including the
+// very first ones, which a check on jobs alone could not.
+func acquireUniqueKey(ctx context.Context, tx *sql.Tx, payloads PayloadOptions, spec domain.JobSpec) (*uniqueHit, error) {
+	u := spec.Unique
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO job_unique_keys (tenant_id, job_type, unique_key, job_id)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE job_id = job_id
+	`, spec.TenantID, spec.Type, u.Key, spec.ID); err != nil {
+		return nil, fmt.Errorf("lock unique key: %w", err)
+	}
+
+	var holder string
+	if err := tx.QueryRowContext(ctx, `
+		SELECT job_id FROM job_unique_keys
+		WHERE tenant_id = ? AND job_type = ? AND unique_key = ?
+		FOR UPDATE
+	`, spec.TenantID, spec.Type, u.Key).Scan(&holder); err != nil {
+		return nil, fmt.Errorf("lock unique key: %w", err)
+	}
+	if holder == spec.ID {
+		return nil, nil
+	}
+
+	var status domain.JobStatus
+	var inWindow bool
+	var idemKey sql.NullString
+	err := tx.QueryRowContext(ctx, `
+		SELECT status, created_at >= DATE_SUB(NOW(6), INTERVAL ? MICROSECOND), idempotency_key
+		FROM jobs
+		WHERE id = ?
+		FOR UPDATE
+	`, u.Window.Microseconds(), holder).Scan(&status, &inWindow, &idemKey)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// The holder was purged; the key is free.
+	case err != nil:
+		return nil, fmt.Errorf("load unique key holder: %w", err)
+	default:
+		// A retry of the request that created holder is an idempotent
+		// replay, not a duplicate; let the insert report it as such.
+		replay := spec.IdempotencyKey != nil && idemKey.Valid && idemKey.String == *spec.IdempotencyKey
+
+		blocks := status == domain.StatusPending || status == domain.StatusBlocked || status == domain.StatusRunning
+		if u.Window > 0 {
+			blocks = inWindow
+		}
+		if blocks && !replay {
+			return applyUniquePolicy(ctx, tx, payloads, spec, holder, status)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE job_unique_keys SET job_id = ?
+		WHERE tenant_id = ? AND job_type = ? AND unique_key = ?
+	`, spec.ID, spec.TenantID, spec.Type, u.Key); err != nil {
+		return nil, fmt.Errorf("take unique key: %w", err)
+	}
+	return nil, nil
+}
+
+func applyUniquePolicy(ctx context.Context, tx *sql.Tx, payloads PayloadOptions, spec domain.JobSpec, holder string, status domain.JobStatus) (*uniqueHit, error) {
+	hit := &uniqueHit{jobID: holder, outcome: domain.OutcomeDeduplicated}
+	if spec.Unique.Policy != domain.UniqueReplace || (status != domain.StatusPending && status != domain.StatusBlocked) {
+		return hit, nil
+	}
+
+	var oldRef sql.NullString
+	if err := tx.QueryRowContext(ctx, `
+		SELECT payload_ref FROM jobs WHERE id = ?
+	`, holder).Scan(&oldRef); err != nil {
+		return nil, fmt.Errorf("load payload ref: %w", err)
+	}
+	payload, err := payloads.store(ctx, holder, spec.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE jobs
+		SET payload = ?, payload_encoding = ?, payload_ref = ?, payload_version = ?
+		WHERE id = ?
+	`, payload.data, payload.encoding, payload.ref, spec.PayloadVersion, holder); err != nil {
+		payloads.discard(payload.ref)
+		return nil, fmt.Errorf("replace payload: %w", err)
+	}
+	if err := insertEvent(ctx, tx, holder, domain.EventReplaced, nil); err != nil {
+		return nil, err
+	}
+	hit.outcome = domain.OutcomeReplaced
+	if oldRef.Valid {
+		hit.replacedBlob = &oldRef.String
+	}
+	return hit, nil
+}
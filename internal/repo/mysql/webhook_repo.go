@@ -0,0 +1,283 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+// WebhookRepo manages the webhook_deliveries outbox.
+// Rows are inserted by JobRepo in the same transaction as the terminal
+// state change and drained by worker.WebhookDispatcher.
+type WebhookRepo struct {
+	db *sql.DB
+}
+
+func NewWebhookRepo(db *sql.DB) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+// enqueueWebhook snapshots jobID into a notification and queues it for
+// delivery if the job has a callback_url. Must run inside the transaction
+// that moved the job to a terminal state.
+func enqueueWebhook(ctx context.Context, tx *sql.Tx, jobID string, event domain.EventType) error {
+	job, err := scanJob(tx.QueryRowContext(ctx, `
+		SELECT `+jobColumns+`
+		FROM jobs
+		WHERE id = ?
+	`, jobID))
+	if err != nil {
+		return fmt.Errorf("load job for webhook: %w", err)
+	}
+	if job == nil || job.CallbackURL == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(domain.JobNotification{
+		Event:        event,
+		JobID:        job.ID,
+		Type:         job.Type,
+		Queue:        job.Queue,
+		Status:       job.Status,
+		Attempts:     job.Attempts,
+		ErrorMessage: job.ErrorMessage,
+		CompletedAt:  job.CompletedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (job_id, tenant_id, url, body, status, next_attempt_at)
+		VALUES (?, ?, ?, ?, 'PENDING', NOW(6))
+	`, job.ID, job.TenantID, *job.CallbackURL, body)
+	if err != nil {
+		return fmt.Errorf("insert webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ClaimDeliveries leases due PENDING deliveries for this worker.
+func (r *WebhookRepo) ClaimDeliveries(
+	ctx context.Context,
+	workerID string,
+	limit int,
+	lease time.Duration,
+	now time.Time,
+) ([]domain.WebhookDelivery, error) {
+	if workerID == "" {
+		return nil, fmt.Errorf("workerID required")
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelReadCommitted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE status = 'PENDING'
+			AND next_attempt_at <= ?
+			AND (locked_until IS NULL OR locked_until <= ?)
+		ORDER BY next_attempt_at ASC
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED
+	`, now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range claimed {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET locked_by = ?, locked_until = ?
+			WHERE id = ?
+		`, workerID, now.Add(lease), d.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// RenewDelivery extends workerID's lease on a claimed delivery to until.
+// It reports false if the lease was lost: another worker claimed the
+// delivery after it expired, or it is no longer PENDING.
+func (r *WebhookRepo) RenewDelivery(ctx context.Context, id int64, workerID string, until time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET locked_until = ?
+		WHERE id = ? AND status = 'PENDING' AND locked_by = ?
+	`, until, id, workerID)
+	if err != nil {
+		return false, fmt.Errorf("renew delivery lease: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// MarkDelivered records a successful attempt by the worker holding the
+// delivery's lease.
+func (r *WebhookRepo) MarkDelivered(ctx context.Context, id int64, workerID string, statusCode int, attempts int, now time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET
+			status = 'DELIVERED',
+			attempts = ?,
+			last_status_code = ?,
+			last_error = NULL,
+			delivered_at = ?,
+			locked_by = NULL,
+			locked_until = NULL
+		WHERE id = ? AND status = 'PENDING' AND locked_by = ?
+	`, attempts, statusCode, now, id, workerID)
+	if err != nil {
+		return fmt.Errorf("mark delivered: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("mark delivered rejected: lease lost")
+	}
+	return nil
+}
+
+// MarkDeliveryFailure records a failed attempt by the worker holding the
+// delivery's lease. A nil nextAttemptAt (or terminal=true) moves the
+// delivery to FAILED, where it stays for inspection.
+func (r *WebhookRepo) MarkDeliveryFailure(
+	ctx context.Context,
+	id int64,
+	workerID string,
+	attempts int,
+	statusCode *int,
+	errMsg string,
+	nextAttemptAt *time.Time,
+	terminal bool,
+) error {
+	status := domain.DeliveryPending
+	var next any = nil
+	if terminal || nextAttemptAt == nil {
+		status = domain.DeliveryFailed
+	} else {
+		next = *nextAttemptAt
+	}
+
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET
+			status = ?,
+			attempts = ?,
+			last_status_code = ?,
+			last_error = ?,
+			next_attempt_at = COALESCE(?, next_attempt_at),
+			locked_by = NULL,
+			locked_until = NULL
+		WHERE id = ? AND status = 'PENDING' AND locked_by = ?
+	`, string(status), attempts, statusCode, errMsg, next, id, workerID)
+	if err != nil {
+		return fmt.Errorf("mark delivery failure: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("mark delivery failure rejected: lease lost")
+	}
+	return nil
+}
+
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, filter domain.DeliveryFilter, limit int) ([]domain.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	where := []string{"1 = 1"}
+	var args []any
+	if filter.TenantID != "" {
+		where = append(where, "tenant_id = ?")
+		args = append(args, filter.TenantID)
+	}
+	if filter.JobID != "" {
+		where = append(where, "job_id = ?")
+		args = append(args, filter.JobID)
+	}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, string(filter.Status))
+	}
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+const deliveryColumns = `
+	id, job_id, url, CAST(body AS CHAR), status,
+	attempts, next_attempt_at, last_status_code, last_error, delivered_at,
+	created_at, updated_at`
+
+func scanDeliveries(rows *sql.Rows) ([]domain.WebhookDelivery, error) {
+	defer rows.Close()
+
+	var out []domain.WebhookDelivery
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		var body string
+		var next, deliveredAt sql.NullTime
+		var code sql.NullInt64
+		var lastErr sql.NullString
+
+		if err := rows.Scan(
+			&d.ID, &d.JobID, &d.URL, &body, &d.Status,
+			&d.Attempts, &next, &code, &lastErr, &deliveredAt,
+			&d.CreatedAt, &d.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+
+		d.Body = []byte(body)
+		if next.Valid {
+			t := next.Time
+			d.NextAttemptAt = &t
+		}
+		if code.Valid {
+			c := int(code.Int64)
+			d.LastCode = &c
+		}
+		if lastErr.Valid {
+			s := lastErr.String
+			d.LastError = &s
+		}
+		if deliveredAt.Valid {
+			t := deliveredAt.Time
+			d.DeliveredAt = &t
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,169 @@
+package mysqlrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"task-scheduler/internal/domain"
+)
+
+type WorkflowRepo struct {
+	db       *sql.DB
+	payloads PayloadOptions
+}
+
+func NewWorkflowRepo(db *sql.DB, payloads PayloadOptions) *WorkflowRepo {
+	return &WorkflowRepo{db: db, payloads: payloads}
+}
+
+func (r *WorkflowRepo) CreateWorkflow(ctx context.Context, spec domain.WorkflowSpec) (*domain.Workflow, error) {
+	if spec.ID == "" {
+		return nil, fmt.Errorf("%w: id required", domain.ErrInvalidInput)
+	}
+	if len(spec.Nodes) == 0 {
+		return nil, fmt.Errorf("%w: workflow has no nodes", domain.ErrInvalidInput)
+	}
+	if spec.TenantID == "" {
+		spec.TenantID = domain.DefaultTenant
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var name *string
+	if spec.Name != "" {
+		name = &spec.Name
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO workflows (id, tenant_id, name, api_key_id)
+		VALUES (?, ?, ?, ?)
+	`, spec.ID, spec.TenantID, name, spec.APIKeyID); err != nil {
+		return nil, fmt.Errorf("insert workflow: %w", err)
+	}
+
+	if err := checkQueuedQuota(ctx, tx, spec.TenantID, len(spec.Nodes)); err != nil {
+		return nil, err
+	}
+
+	// Nodes come in topological order, so every parent is already inserted
+	// (and PENDING or BLOCKED) when its children are.
+	for _, node := range spec.Nodes {
+		node.TenantID = spec.TenantID
+		node.WorkflowID = &spec.ID
+		if node.Queue == "" {
+			node.Queue = domain.DefaultQueue
+		}
+		if node.MaxAttempts <= 0 {
+			node.MaxAttempts = 3
+		}
+		if node.PayloadVersion <= 0 {
+			node.PayloadVersion = 1
+		}
+		if node.OnParentFailure == "" {
+			node.OnParentFailure = domain.ParentFailureCascade
+		}
+		if err := insertJob(ctx, tx, r.payloads, node, nil, nil); err != nil {
+			return nil, fmt.Errorf("insert node %s: %w", node.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetWorkflow(ctx, spec.TenantID, spec.ID)
+}
+
+func (r *WorkflowRepo) GetWorkflow(ctx context.Context, tenantID, id string) (*domain.Workflow, error) {
+	wf := domain.Workflow{ID: id, TenantID: tenantID}
+	var name sql.NullString
+	err := r.db.QueryRowContext(ctx, `
+		SELECT name, created_at FROM workflows WHERE id = ? AND tenant_id = ?
+	`, id, tenantID).Scan(&name, &wf.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	wf.Name = name.String
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+jobColumns+`
+		FROM jobs
+		WHERE workflow_id = ? AND tenant_id = ?
+		ORDER BY created_at, id
+	`, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := map[string]string{} // job ID -> node key
+	index := map[string]int{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		key := j.ID
+		if j.WorkflowNode != nil {
+			key = *j.WorkflowNode
+		}
+		keys[j.ID] = key
+		index[j.ID] = len(wf.Nodes)
+		wf.Nodes = append(wf.Nodes, domain.WorkflowNode{
+			Key:          key,
+			JobID:        j.ID,
+			Type:         j.Type,
+			Queue:        j.Queue,
+			Status:       j.Status,
+			Attempts:     j.Attempts,
+			DependsOn:    []string{},
+			ErrorMessage: j.ErrorMessage,
+			StartedAt:    j.StartedAt,
+			CompletedAt:  j.CompletedAt,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	deps, err := r.db.QueryContext(ctx, `
+		SELECT d.job_id, d.parent_id
+		FROM job_dependencies d
+		JOIN jobs j ON j.id = d.job_id
+		WHERE j.workflow_id = ? AND j.tenant_id = ?
+		ORDER BY d.job_id, d.parent_id
+	`, id, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer deps.Close()
+	for deps.Next() {
+		var jobID, parentID string
+		if err := deps.Scan(&jobID, &parentID); err != nil {
+			return nil, err
+		}
+		i, ok := index[jobID]
+		if !ok {
+			continue
+		}
+		parent, ok := keys[parentID]
+		if !ok {
+			// A parent outside the workflow; show its job ID.
+			parent = parentID
+		}
+		wf.Nodes[i].DependsOn = append(wf.Nodes[i].DependsOn, parent)
+	}
+	if err := deps.Err(); err != nil {
+		return nil, err
+	}
+
+	wf.Status = domain.WorkflowStatus(wf.Nodes)
+	return &wf, nil
+}
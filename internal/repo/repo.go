@@ -2,6 +2,7 @@ package repo
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"task-scheduler/internal/domain"
@@ -9,13 +10,46 @@ import (
 
 type JobRepository interface {
 	// API operations
-	CreateJob(ctx context.Context, id, jobType string, payload []byte, maxAttempts int, idempotencyKey *string) (*domain.Job, error)
-	GetJobByID(ctx context.Context, id string) (*domain.Job, error)
-	GetJobByIdempotencyKey(ctx context.Context, key string) (*domain.Job, error)
+	// Every API read is scoped to a tenant; jobs of other tenants look like they do not exist.
+
+	// CreateJob inserts a PENDING job for spec.TenantID. If spec.IdempotencyKey is already
+	// held by an identical request it returns that job with domain.OutcomeReplayed; a
+	// different request under the same live key yields domain.ErrConflict. If spec.Unique
+	// matches a blocking job, that job is returned (and possibly updated) per its policy.
+	// Tenants over their queued quota get domain.ErrQuotaExceeded.
+	CreateJob(ctx context.Context, spec domain.JobSpec) (*domain.Job, domain.CreateOutcome, error)
+
+	// CreateJobs creates many jobs of one tenant with chunked multi-row inserts.
+	// Each item gets its own result: created, replayed under its idempotency key,
+	// or an error that does not affect the other items.
+	CreateJobs(ctx context.Context, specs []domain.JobSpec) []domain.BulkItemResult
+	GetJobByID(ctx context.Context, tenantID, id string) (*domain.Job, error)
+	GetJobByIdempotencyKey(ctx context.Context, tenantID, key string) (*domain.Job, error)
+
+	// CancelJob moves a PENDING, BLOCKED or RUNNING job to CANCELLED.
+	// Returns domain.ErrNotFound for unknown jobs and domain.ErrConflict for finished ones.
+	CancelJob(ctx context.Context, tenantID, id string, now time.Time) (*domain.Job, error)
+
+	// ListJobs returns the tenant's jobs matching filter, newest first.
+	ListJobs(ctx context.Context, filter domain.JobFilter, limit int) ([]domain.Job, error)
+
+	// RetryJob moves a FAILED or CANCELLED job back to PENDING with fresh attempts.
+	// Returns domain.ErrNotFound for unknown jobs and domain.ErrConflict if it cannot be retried.
+	RetryJob(ctx context.Context, tenantID, id string, now time.Time) (*domain.Job, error)
+
+	// PurgeJobs deletes the finished jobs among ids with their events, steps and
+	// offloaded payloads, skipping the rest; an empty tenantID matches every
+	// tenant. Returns the number of jobs deleted.
+	PurgeJobs(ctx context.Context, tenantID string, ids []string) (int, error)
+
+	// CountPayloadVersions reports PENDING and BLOCKED jobs by type and payload
+	// version; an empty tenantID counts every tenant.
+	CountPayloadVersions(ctx context.Context, tenantID string) ([]domain.PayloadVersionCount, error)
 
 	// Worker operations
 	// ClaimJobs atomically "leases" jobs for this worker to execute.
 	// It should return jobs already moved to RUNNING with locked_by/locked_until set.
+	// Work is spread across tenants and no tenant exceeds its max_running quota.
 	ClaimJobs(ctx context.Context, workerID string, limit int, lease time.Duration, now time.Time) ([]domain.Job, error)
 
 	// Heartbeat extends the lease for long-running jobs (optional but production-grade).
@@ -27,4 +61,107 @@ type JobRepository interface {
 
 	// Execution idempotency for side-effects (optional now, but we’ll use it soon)
 	RecordStepOnce(ctx context.Context, jobID string, stepKey string, resultHash *string) (inserted bool, err error)
+
+	// Step memoization for multi-step handlers.
+	// LoadSteps returns a job's completed steps in completion order.
+	LoadSteps(ctx context.Context, jobID string) ([]domain.StepRecord, error)
+	// SaveStep records a step once; if it was already saved, the stored output is returned.
+	SaveStep(ctx context.Context, jobID, step string, output json.RawMessage) (json.RawMessage, error)
+
+	// Saga compensation. A job that fails terminally with completed steps
+	// to undo moves RUNNING -> COMPENSATING -> COMPENSATED | COMPENSATION_FAILED.
+	MarkCompensating(ctx context.Context, jobID string, attempts int, errMsg string) error
+	RecordCompensation(ctx context.Context, jobID, step string) error
+	MarkCompensationRetry(ctx context.Context, jobID string, compensationAttempts int, nextRunAt time.Time, errMsg string) error
+	FinishCompensation(ctx context.Context, jobID string, compensationAttempts int, failed bool, errMsg *string, completedAt time.Time) error
+}
+
+// EventRepository reads the durable job lifecycle log.
+// Events are written by JobRepository in the same transaction as the state change.
+type EventRepository interface {
+	// ListEvents returns events with ID > afterID in ascending order. IDs follow
+	// commit order, so a cursor never skips an event committed after it.
+	ListEvents(ctx context.Context, afterID int64, filter domain.EventFilter, limit int) ([]domain.JobEvent, error)
+	// LatestEventID returns the highest event ID handed out (0 if there are none).
+	LatestEventID(ctx context.Context) (int64, error)
+}
+
+// WebhookRepository exposes the completion-webhook outbox for inspection.
+type WebhookRepository interface {
+	ListDeliveries(ctx context.Context, filter domain.DeliveryFilter, limit int) ([]domain.WebhookDelivery, error)
+}
+
+// APIKeyRepository stores hashed API keys.
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key domain.APIKey, hash string) error
+	// GetAPIKeyByHash returns nil (no error) for unknown or revoked keys.
+	GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error)
+	// ListAPIKeys lists a tenant's keys; an empty tenantID lists all tenants.
+	ListAPIKeys(ctx context.Context, tenantID string) ([]domain.APIKey, error)
+	// RevokeAPIKey returns domain.ErrNotFound if the key does not exist in tenantID
+	// (any tenant when tenantID is empty).
+	RevokeAPIKey(ctx context.Context, tenantID, id string, now time.Time) error
+}
+
+// TenantRepository manages per-tenant quotas.
+type TenantRepository interface {
+	GetQuota(ctx context.Context, tenantID string) (*domain.TenantQuota, error)
+	SetQuota(ctx context.Context, q domain.TenantQuota) error
+	ListQuotas(ctx context.Context) ([]domain.TenantQuota, error)
+}
+
+// RateLimitRepository manages the token buckets ClaimJobs enforces.
+type RateLimitRepository interface {
+	ListRateLimits(ctx context.Context) ([]domain.RateLimit, error)
+	// SetRateLimit creates or updates a bucket; new buckets start full.
+	SetRateLimit(ctx context.Context, rl domain.RateLimit) error
+	// DeleteRateLimit returns domain.ErrNotFound if no such bucket exists.
+	DeleteRateLimit(ctx context.Context, jobType, queue string) error
+}
+
+// ConcurrencyLimitRepository manages the per-type RUNNING caps ClaimJobs enforces.
+type ConcurrencyLimitRepository interface {
+	ListConcurrencyLimits(ctx context.Context) ([]domain.ConcurrencyLimit, error)
+	SetConcurrencyLimit(ctx context.Context, l domain.ConcurrencyLimit) error
+	// DeleteConcurrencyLimit returns domain.ErrNotFound if the type has no limit.
+	DeleteConcurrencyLimit(ctx context.Context, jobType string) error
+}
+
+// WorkflowRepository stores job DAGs submitted as one unit.
+type WorkflowRepository interface {
+	// CreateWorkflow inserts the workflow and all its nodes in one transaction.
+	CreateWorkflow(ctx context.Context, spec domain.WorkflowSpec) (*domain.Workflow, error)
+	// GetWorkflow returns nil (no error) if the workflow does not exist in tenantID.
+	GetWorkflow(ctx context.Context, tenantID, id string) (*domain.Workflow, error)
+}
+
+// BatchRepository stores fan-out batches and their progress counters.
+type BatchRepository interface {
+	// CreateBatch inserts the batch and all its jobs in one transaction.
+	CreateBatch(ctx context.Context, spec domain.BatchSpec) (*domain.Batch, error)
+	// GetBatch returns nil (no error) if the batch does not exist in tenantID.
+	GetBatch(ctx context.Context, tenantID, id string) (*domain.Batch, error)
+}
+
+// QueueRepository pauses queues and reports their job counts.
+type QueueRepository interface {
+	PauseQueue(ctx context.Context, tenantID, queue string) error
+	ResumeQueue(ctx context.Context, tenantID, queue string) error
+	ListQueues(ctx context.Context, tenantID string) ([]domain.QueueStats, error)
+}
+
+// PayloadSchemaRepository stores the per-type payload schemas set through the API.
+type PayloadSchemaRepository interface {
+	ListPayloadSchemas(ctx context.Context) ([]domain.PayloadSchema, error)
+	// GetPayloadSchema returns nil (no error) if the type has no stored schema.
+	GetPayloadSchema(ctx context.Context, jobType string) (*domain.PayloadSchema, error)
+	SetPayloadSchema(ctx context.Context, s domain.PayloadSchema) error
+	// DeletePayloadSchema returns domain.ErrNotFound if the type has no stored schema.
+	DeletePayloadSchema(ctx context.Context, jobType string) error
+}
+
+// RetentionRepository reports the retention sweeps run by the workers.
+type RetentionRepository interface {
+	// LastRetentionRun returns nil (no error) if no sweep has run yet.
+	LastRetentionRun(ctx context.Context) (*domain.RetentionRun, error)
 }
@@ -3,40 +3,398 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
+	"time"
 
 	"task-scheduler/internal/domain"
 	"task-scheduler/internal/repo"
 )
 
+// Policy holds the business rules every new job must satisfy.
+type Policy struct {
+	// MaxPayloadBytes caps the encoded payload size; 0 means no cap.
+	MaxPayloadBytes int
+	// AllowedTypes, when non-empty, is the only job types accepted.
+	AllowedTypes []string
+	// DefaultMaxAttempts applies when a job does not set max_attempts.
+	DefaultMaxAttempts int
+	// MaxAttemptsLimit caps max_attempts; 0 means no cap.
+	MaxAttemptsLimit int
+	// IdempotencyTTL is how long an idempotency key stays reserved (0 = forever).
+	IdempotencyTTL time.Duration
+}
+
+// DefaultPolicy is used for zero fields of the policy given to NewJobService.
+var DefaultPolicy = Policy{
+	MaxPayloadBytes:    1 << 20,
+	DefaultMaxAttempts: 3,
+	MaxAttemptsLimit:   25,
+}
+
+const (
+	maxQueueLen          = 64
+	maxTypeLen           = 50
+	maxKeyLen            = 255
+	maxParents           = 100
+	maxRetryMatchingJobs = 1000
+)
+
 type JobService struct {
-	Repo repo.JobRepository
+	Repo   repo.JobRepository
+	Policy Policy
+	// Schemas, when set, validates payloads against their type's schema.
+	Schemas *SchemaRegistry
 }
 
-func NewJobService(r repo.JobRepository) *JobService {
-	return &JobService{Repo: r}
+func NewJobService(r repo.JobRepository, p Policy) *JobService {
+	if p.MaxPayloadBytes == 0 {
+		p.MaxPayloadBytes = DefaultPolicy.MaxPayloadBytes
+	}
+	if p.DefaultMaxAttempts == 0 {
+		p.DefaultMaxAttempts = DefaultPolicy.DefaultMaxAttempts
+	}
+	if p.MaxAttemptsLimit == 0 {
+		p.MaxAttemptsLimit = DefaultPolicy.MaxAttemptsLimit
+	}
+	return &JobService{Repo: r, Policy: p}
 }
 
-func (s *JobService) Create(ctx context.Context, id, jobType string, payload json.RawMessage, maxAttempts int, idemKey *string) (*domain.Job, error) {
-	if strings.TrimSpace(id) == "" {
-		return nil, fmt.Errorf("%w: id required", domain.ErrInvalidInput)
+// Prepare validates spec against the policy and fills in defaults. It is
+// applied by Create and CreateMany, and by callers that store job specs
+// through other repositories (batches, workflows). Rule violations are
+// *domain.ValidationError values.
+func (s *JobService) Prepare(ctx context.Context, spec *domain.JobSpec) error {
+	if err := s.prepare(spec); err != nil {
+		return err
 	}
-	if strings.TrimSpace(jobType) == "" {
-		return nil, fmt.Errorf("%w: type required", domain.ErrInvalidInput)
+	if s.Schemas != nil {
+		return s.Schemas.Validate(ctx, spec.Type, spec.Payload)
 	}
+	return nil
+}
+
+// PrepareCallback is Prepare for a batch callback. The callback runs with
+// the batch summary as its payload, so its own payload is optional and the
+// type's schema is checked by the worker against the summary instead.
+func (s *JobService) PrepareCallback(spec *domain.JobSpec) error {
+	payload := spec.Payload
 	if len(payload) == 0 {
-		return nil, fmt.Errorf("%w: payload required", domain.ErrInvalidInput)
+		spec.Payload = json.RawMessage(`{}`)
+	}
+	err := s.prepare(spec)
+	spec.Payload = payload
+	return err
+}
+
+func (s *JobService) prepare(spec *domain.JobSpec) error {
+	spec.Type = strings.TrimSpace(spec.Type)
+	spec.Queue = strings.TrimSpace(spec.Queue)
+
+	var missing []domain.FieldError
+	if spec.Type == "" {
+		missing = append(missing, domain.FieldError{Field: "type", Message: "required"})
+	}
+	if len(spec.Payload) == 0 {
+		missing = append(missing, domain.FieldError{Field: "payload", Message: "required"})
+	}
+	if len(missing) > 0 {
+		return &domain.ValidationError{Code: "type_and_payload_required", Message: "type and payload are required", Fields: missing}
+	}
+
+	if len(spec.Type) > maxTypeLen {
+		return domain.Invalid("invalid_type", "type", fmt.Sprintf("must be at most %d characters", maxTypeLen))
+	}
+	if len(s.Policy.AllowedTypes) > 0 && !contains(s.Policy.AllowedTypes, spec.Type) {
+		return domain.Invalid("type_not_allowed", "type", fmt.Sprintf("%q is not an accepted job type", spec.Type))
+	}
+	if spec.PayloadVersion < 0 {
+		return domain.Invalid("invalid_payload_version", "payload_version", "must be >= 1")
+	}
+	if spec.PayloadVersion == 0 {
+		spec.PayloadVersion = 1
+	}
+	if s.Policy.MaxPayloadBytes > 0 && len(spec.Payload) > s.Policy.MaxPayloadBytes {
+		return domain.Invalid("payload_too_large", "payload", fmt.Sprintf("must be at most %d bytes", s.Policy.MaxPayloadBytes))
+	}
+	if !json.Valid(spec.Payload) {
+		return domain.Invalid("invalid_payload", "payload", "must be valid JSON")
+	}
+
+	if spec.Queue == "" {
+		spec.Queue = domain.DefaultQueue
+	}
+	if len(spec.Queue) > maxQueueLen {
+		return domain.Invalid("invalid_queue", "queue", fmt.Sprintf("must be at most %d characters", maxQueueLen))
+	}
+
+	if spec.MaxAttempts == 0 {
+		spec.MaxAttempts = s.Policy.DefaultMaxAttempts
+	}
+	if spec.MaxAttempts < 1 || (s.Policy.MaxAttemptsLimit > 0 && spec.MaxAttempts > s.Policy.MaxAttemptsLimit) {
+		return domain.Invalid("invalid_max_attempts", "max_attempts", fmt.Sprintf("must be between 1 and %d", s.Policy.MaxAttemptsLimit))
+	}
+
+	if spec.Priority < domain.MinPriority || spec.Priority > domain.MaxPriority {
+		return domain.Invalid("invalid_priority", "priority", fmt.Sprintf("must be between %d and %d", domain.MinPriority, domain.MaxPriority))
+	}
+
+	if spec.CallbackURL != nil {
+		cb := strings.TrimSpace(*spec.CallbackURL)
+		switch {
+		case cb == "":
+			spec.CallbackURL = nil
+		case !validCallbackURL(cb):
+			return domain.Invalid("invalid_callback_url", "callback_url", "must be an absolute http(s) URL")
+		default:
+			spec.CallbackURL = &cb
+		}
+	}
+
+	if spec.ConcurrencyKey != nil {
+		ck := strings.TrimSpace(*spec.ConcurrencyKey)
+		switch {
+		case ck == "":
+			spec.ConcurrencyKey = nil
+		case len(ck) > maxKeyLen:
+			return domain.Invalid("invalid_concurrency_key", "concurrency_key", fmt.Sprintf("must be at most %d characters", maxKeyLen))
+		default:
+			spec.ConcurrencyKey = &ck
+		}
+	}
+
+	if spec.IdempotencyKey != nil {
+		spec.IdempotencyKey = NormalizeIdempotencyKey(*spec.IdempotencyKey)
+		if spec.IdempotencyKey != nil && len(*spec.IdempotencyKey) > maxKeyLen {
+			return domain.Invalid("invalid_idempotency_key", "idempotency_key", fmt.Sprintf("must be at most %d characters", maxKeyLen))
+		}
+	}
+	if spec.IdempotencyTTL == 0 {
+		spec.IdempotencyTTL = s.Policy.IdempotencyTTL
+	}
+
+	if u := spec.Unique; u != nil {
+		if u.Key == "" || len(u.Key) > domain.MaxUniqueKeyLen {
+			return domain.Invalid("invalid_unique", "unique.key", fmt.Sprintf("must be 1 to %d characters", domain.MaxUniqueKeyLen))
+		}
+		switch u.Policy {
+		case "":
+			u.Policy = domain.UniqueReturnExisting
+		case domain.UniqueReturnExisting, domain.UniqueReplace:
+		default:
+			return domain.Invalid("invalid_unique", "unique.policy", `must be "return_existing" or "replace"`)
+		}
+		if u.Window < 0 {
+			return domain.Invalid("invalid_unique", "unique.window_seconds", "must be >= 0")
+		}
+	}
+
+	parents, err := dedupeParents(spec.DependsOn)
+	if err != nil {
+		return err
+	}
+	spec.DependsOn = parents
+	switch spec.OnParentFailure {
+	case "", domain.ParentFailureCascade, domain.ParentFailureRun:
+	default:
+		return domain.Invalid("invalid_depends_on", "on_parent_failure", `must be "cascade" or "run"`)
 	}
-	if maxAttempts <= 0 {
-		maxAttempts = 3
+	return nil
+}
+
+func (s *JobService) Create(ctx context.Context, spec domain.JobSpec) (*domain.Job, domain.CreateOutcome, error) {
+	if strings.TrimSpace(spec.ID) == "" {
+		return nil, "", fmt.Errorf("%w: id required", domain.ErrInvalidInput)
+	}
+	if err := s.Prepare(ctx, &spec); err != nil {
+		return nil, "", err
+	}
+	return s.Repo.CreateJob(ctx, spec)
+}
+
+// CreateMany creates simple jobs of one tenant in bulk. Items failing the
+// policy get their error in place; the rest are inserted together.
+func (s *JobService) CreateMany(ctx context.Context, specs []domain.JobSpec) []domain.BulkItemResult {
+	results := make([]domain.BulkItemResult, len(specs))
+	valid := make([]domain.JobSpec, 0, len(specs))
+	index := make([]int, 0, len(specs))
+	for i := range specs {
+		spec := specs[i]
+		if strings.TrimSpace(spec.ID) == "" {
+			results[i].Err = fmt.Errorf("%w: id required", domain.ErrInvalidInput)
+			continue
+		}
+		if err := s.Prepare(ctx, &spec); err != nil {
+			results[i].Err = err
+			continue
+		}
+		valid = append(valid, spec)
+		index = append(index, i)
 	}
-	return s.Repo.CreateJob(ctx, id, jobType, payload, maxAttempts, idemKey)
+	if len(valid) == 0 {
+		return results
+	}
+	for n, res := range s.Repo.CreateJobs(ctx, valid) {
+		results[index[n]] = res
+	}
+	return results
 }
 
-func (s *JobService) Get(ctx context.Context, id string) (*domain.Job, error) {
+func (s *JobService) Get(ctx context.Context, tenantID, id string) (*domain.Job, error) {
 	if strings.TrimSpace(id) == "" {
 		return nil, fmt.Errorf("%w: id required", domain.ErrInvalidInput)
 	}
-	return s.Repo.GetJobByID(ctx, id)
+	return s.Repo.GetJobByID(ctx, tenantID, id)
+}
+
+func (s *JobService) List(ctx context.Context, filter domain.JobFilter, limit int) ([]domain.Job, error) {
+	if filter.Status != "" && !knownStatus(filter.Status) {
+		return nil, domain.Invalid("invalid_status", "status", "unknown job status")
+	}
+	return s.Repo.ListJobs(ctx, filter, limit)
+}
+
+func (s *JobService) Cancel(ctx context.Context, tenantID, id string) (*domain.Job, error) {
+	return s.Repo.CancelJob(ctx, tenantID, id, time.Now())
+}
+
+func (s *JobService) Retry(ctx context.Context, tenantID, id string) (*domain.Job, error) {
+	return s.Repo.RetryJob(ctx, tenantID, id, time.Now())
+}
+
+// Purge deletes a finished job and everything stored for it. Returns
+// domain.ErrNotFound for unknown jobs and domain.ErrConflict for jobs that
+// have not finished.
+func (s *JobService) Purge(ctx context.Context, tenantID, id string) error {
+	job, err := s.Repo.GetJobByID(ctx, tenantID, id)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return domain.ErrNotFound
+	}
+	if !job.Status.Terminal() {
+		return fmt.Errorf("%w: job is %s", domain.ErrConflict, job.Status)
+	}
+	n, err := s.Repo.PurgeJobs(ctx, tenantID, []string{id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// Retried since it was loaded.
+		return fmt.Errorf("%w: job is no longer finished", domain.ErrConflict)
+	}
+	return nil
+}
+
+// PayloadVersions reports queued jobs by type and payload version, so an old
+// version can be retired once none are left. An empty tenantID counts every
+// tenant.
+func (s *JobService) PayloadVersions(ctx context.Context, tenantID string) ([]domain.PayloadVersionCount, error) {
+	return s.Repo.CountPayloadVersions(ctx, tenantID)
+}
+
+// RetryResult reports a RetryMatching run.
+type RetryResult struct {
+	Retried       []string `json:"retried"`
+	Skipped       int      `json:"skipped"`
+	QuotaExceeded bool     `json:"quota_exceeded,omitempty"`
+}
+
+// RetryMatching requeues up to limit FAILED or CANCELLED jobs matching
+// filter, newest first. allow filters out jobs the caller may not touch.
+// Jobs that cannot be retried are skipped; a full queued quota stops the run.
+func (s *JobService) RetryMatching(ctx context.Context, filter domain.JobFilter, limit int, allow func(domain.Job) bool) (*RetryResult, error) {
+	if filter.Status == "" {
+		filter.Status = domain.StatusFailed
+	}
+	if filter.Status != domain.StatusFailed && filter.Status != domain.StatusCancelled {
+		return nil, domain.Invalid("invalid_status", "status", "must be FAILED or CANCELLED")
+	}
+	if limit <= 0 || limit > maxRetryMatchingJobs {
+		limit = 100
+	}
+
+	res := &RetryResult{Retried: []string{}}
+	for len(res.Retried) < limit {
+		page, err := s.Repo.ListJobs(ctx, filter, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, j := range page {
+			if len(res.Retried) == limit {
+				break
+			}
+			if allow != nil && !allow(j) {
+				continue
+			}
+			_, err := s.Repo.RetryJob(ctx, filter.TenantID, j.ID, time.Now())
+			switch {
+			case errors.Is(err, domain.ErrConflict), errors.Is(err, domain.ErrNotFound):
+				res.Skipped++
+			case errors.Is(err, domain.ErrQuotaExceeded):
+				// Nothing else will fit either.
+				res.QuotaExceeded = true
+				return res, nil
+			case err != nil:
+				return nil, err
+			default:
+				res.Retried = append(res.Retried, j.ID)
+			}
+		}
+		if len(page) < limit {
+			break
+		}
+		filter.Before = page[len(page)-1].ID
+	}
+	return res, nil
+}
+
+// dedupeParents trims and de-duplicates parent job IDs.
+func dedupeParents(refs []string) ([]string, error) {
+	if len(refs) > maxParents {
+		return nil, domain.Invalid("invalid_depends_on", "depends_on", fmt.Sprintf("at most %d parents", maxParents))
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			return nil, domain.Invalid("invalid_depends_on", "depends_on", "must not contain empty IDs")
+		}
+		if !seen[ref] {
+			seen[ref] = true
+			out = append(out, ref)
+		}
+	}
+	return out, nil
+}
+
+func validCallbackURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+func knownStatus(s domain.JobStatus) bool {
+	switch s {
+	case domain.StatusPending, domain.StatusBlocked, domain.StatusRunning,
+		domain.StatusSuccess, domain.StatusFailed, domain.StatusCancelled,
+		domain.StatusCompensating, domain.StatusCompensated, domain.StatusCompensationFailed:
+		return true
+	}
+	return false
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"task-scheduler/internal/domain"
+	"task-scheduler/internal/repo"
+)
+
+// fakeJobRepo records the specs JobService hands to the repository. Methods
+// the tests do not need are left to the embedded nil interface and panic.
+type fakeJobRepo struct {
+	repo.JobRepository
+	created []domain.JobSpec
+}
+
+func (f *fakeJobRepo) CreateJob(_ context.Context, spec domain.JobSpec) (*domain.Job, domain.CreateOutcome, error) {
+	f.created = append(f.created, spec)
+	return &domain.Job{ID: spec.ID, TenantID: spec.TenantID, Type: spec.Type}, domain.OutcomeCreated, nil
+}
+
+func (f *fakeJobRepo) CreateJobs(_ context.Context, specs []domain.JobSpec) []domain.BulkItemResult {
+	results := make([]domain.BulkItemResult, len(specs))
+	for i, spec := range specs {
+		f.created = append(f.created, spec)
+		results[i] = domain.BulkItemResult{JobID: spec.ID, Outcome: domain.OutcomeCreated}
+	}
+	return results
+}
+
+func strPtr(s string) *string { return &s }
+
+func validSpec() domain.JobSpec {
+	return domain.JobSpec{
+		ID:       "job-1",
+		TenantID: "acme",
+		Type:     "email",
+		Payload:  json.RawMessage(`{"to":"a@example.com"}`),
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   Policy
+		edit     func(*domain.JobSpec)
+		wantCode string // "" if the job is created
+		check    func(*testing.T, domain.JobSpec)
+	}{
+		{
+			name:     "type and payload required",
+			edit:     func(s *domain.JobSpec) { s.Type, s.Payload = " ", nil },
+			wantCode: "type_and_payload_required",
+		},
+		{
+			name:   "payload at the size cap",
+			policy: Policy{MaxPayloadBytes: 10},
+			edit:   func(s *domain.JobSpec) { s.Payload = json.RawMessage(`"12345678"`) },
+		},
+		{
+			name:     "payload over the size cap",
+			policy:   Policy{MaxPayloadBytes: 10},
+			edit:     func(s *domain.JobSpec) { s.Payload = json.RawMessage(`"123456789"`) },
+			wantCode: "payload_too_large",
+		},
+		{
+			name:     "payload must be JSON",
+			edit:     func(s *domain.JobSpec) { s.Payload = json.RawMessage(`{`) },
+			wantCode: "invalid_payload",
+		},
+		{
+			name:   "allowed type",
+			policy: Policy{AllowedTypes: []string{"sms", "email"}},
+			edit:   func(s *domain.JobSpec) { s.Type = " email " },
+			check: func(t *testing.T, s domain.JobSpec) {
+				if s.Type != "email" {
+					t.Errorf("type = %q, want trimmed %q", s.Type, "email")
+				}
+			},
+		},
+		{
+			name:     "type outside the allowlist",
+			policy:   Policy{AllowedTypes: []string{"sms"}},
+			wantCode: "type_not_allowed",
+		},
+		{
+			name:     "type too long",
+			edit:     func(s *domain.JobSpec) { s.Type = strings.Repeat("t", maxTypeLen+1) },
+			wantCode: "invalid_type",
+		},
+		{
+			name: "defaults",
+			check: func(t *testing.T, s domain.JobSpec) {
+				if s.MaxAttempts != DefaultPolicy.DefaultMaxAttempts {
+					t.Errorf("max_attempts = %d, want %d", s.MaxAttempts, DefaultPolicy.DefaultMaxAttempts)
+				}
+				if s.Queue != domain.DefaultQueue {
+					t.Errorf("queue = %q, want %q", s.Queue, domain.DefaultQueue)
+				}
+				if s.PayloadVersion != 1 {
+					t.Errorf("payload_version = %d, want 1", s.PayloadVersion)
+				}
+			},
+		},
+		{
+			name:   "policy default max_attempts",
+			policy: Policy{DefaultMaxAttempts: 7},
+			check: func(t *testing.T, s domain.JobSpec) {
+				if s.MaxAttempts != 7 {
+					t.Errorf("max_attempts = %d, want 7", s.MaxAttempts)
+				}
+			},
+		},
+		{
+			name:   "max_attempts at the limit",
+			policy: Policy{MaxAttemptsLimit: 5},
+			edit:   func(s *domain.JobSpec) { s.MaxAttempts = 5 },
+		},
+		{
+			name:     "max_attempts over the limit",
+			policy:   Policy{MaxAttemptsLimit: 5},
+			edit:     func(s *domain.JobSpec) { s.MaxAttempts = 6 },
+			wantCode: "invalid_max_attempts",
+		},
+		{
+			name:     "negative max_attempts",
+			edit:     func(s *domain.JobSpec) { s.MaxAttempts = -1 },
+			wantCode: "invalid_max_attempts",
+		},
+		{
+			name: "priority at the top of the range",
+			edit: func(s *domain.JobSpec) { s.Priority = domain.MaxPriority },
+		},
+		{
+			name:     "priority above range",
+			edit:     func(s *domain.JobSpec) { s.Priority = domain.MaxPriority + 1 },
+			wantCode: "invalid_priority",
+		},
+		{
+			name:     "priority below range",
+			edit:     func(s *domain.JobSpec) { s.Priority = domain.MinPriority - 1 },
+			wantCode: "invalid_priority",
+		},
+		{
+			name: "callback URL trimmed",
+			edit: func(s *domain.JobSpec) { s.CallbackURL = strPtr(" https://example.com/hook ") },
+			check: func(t *testing.T, s domain.JobSpec) {
+				if s.CallbackURL == nil || *s.CallbackURL != "https://example.com/hook" {
+					t.Errorf("callback_url = %v, want trimmed URL", s.CallbackURL)
+				}
+			},
+		},
+		{
+			name: "blank callback URL dropped",
+			edit: func(s *domain.JobSpec) { s.CallbackURL = strPtr("  ") },
+			check: func(t *testing.T, s domain.JobSpec) {
+				if s.CallbackURL != nil {
+					t.Errorf("callback_url = %q, want nil", *s.CallbackURL)
+				}
+			},
+		},
+		{
+			name:     "callback URL scheme",
+			edit:     func(s *domain.JobSpec) { s.CallbackURL = strPtr("ftp://example.com/hook") },
+			wantCode: "invalid_callback_url",
+		},
+		{
+			name:     "relative callback URL",
+			edit:     func(s *domain.JobSpec) { s.CallbackURL = strPtr("/hook") },
+			wantCode: "invalid_callback_url",
+		},
+		{
+			name: "unique default policy",
+			edit: func(s *domain.JobSpec) { s.Unique = &domain.UniqueSpec{Key: "user-1"} },
+			check: func(t *testing.T, s domain.JobSpec) {
+				if s.Unique.Policy != domain.UniqueReturnExisting {
+					t.Errorf("unique.policy = %q, want %q", s.Unique.Policy, domain.UniqueReturnExisting)
+				}
+			},
+		},
+		{
+			name:     "unique key required",
+			edit:     func(s *domain.JobSpec) { s.Unique = &domain.UniqueSpec{} },
+			wantCode: "invalid_unique",
+		},
+		{
+			name: "unique key too long",
+			edit: func(s *domain.JobSpec) {
+				s.Unique = &domain.UniqueSpec{Key: strings.Repeat("k", domain.MaxUniqueKeyLen+1)}
+			},
+			wantCode: "invalid_unique",
+		},
+		{
+			name:     "unknown unique policy",
+			edit:     func(s *domain.JobSpec) { s.Unique = &domain.UniqueSpec{Key: "k", Policy: "merge"} },
+			wantCode: "invalid_unique",
+		},
+		{
+			name:     "negative unique window",
+			edit:     func(s *domain.JobSpec) { s.Unique = &domain.UniqueSpec{Key: "k", Window: -1} },
+			wantCode: "invalid_unique",
+		},
+		{
+			name: "depends_on trimmed and deduplicated",
+			edit: func(s *domain.JobSpec) { s.DependsOn = []string{" a ", "b", "a"} },
+			check: func(t *testing.T, s domain.JobSpec) {
+				if strings.Join(s.DependsOn, ",") != "a,b" {
+					t.Errorf("depends_on = %q, want [a b]", s.DependsOn)
+				}
+			},
+		},
+		{
+			name:     "depends_on empty ID",
+			edit:     func(s *domain.JobSpec) { s.DependsOn = []string{"a", " "} },
+			wantCode: "invalid_depends_on",
+		},
+		{
+			name:     "too many parents",
+			edit:     func(s *domain.JobSpec) { s.DependsOn = make([]string, maxParents+1) },
+			wantCode: "invalid_depends_on",
+		},
+		{
+			name:     "unknown on_parent_failure",
+			edit:     func(s *domain.JobSpec) { s.DependsOn, s.OnParentFailure = []string{"a"}, "ignore" },
+			wantCode: "invalid_depends_on",
+		},
+		{
+			name: "idempotency key normalized",
+			edit: func(s *domain.JobSpec) { s.IdempotencyKey = strPtr("  order-42 ") },
+			check: func(t *testing.T, s domain.JobSpec) {
+				if s.IdempotencyKey == nil || *s.IdempotencyKey != "order-42" {
+					t.Errorf("idempotency_key = %v, want %q", s.IdempotencyKey, "order-42")
+				}
+			},
+		},
+		{
+			name:     "idempotency key too long",
+			edit:     func(s *domain.JobSpec) { s.IdempotencyKey = strPtr(strings.Repeat("k", maxKeyLen+1)) },
+			wantCode: "invalid_idempotency_key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &fakeJobRepo{}
+			svc := NewJobService(r, tt.policy)
+			spec := validSpec()
+			if tt.edit != nil {
+				tt.edit(&spec)
+			}
+
+			_, _, err := svc.Create(context.Background(), spec)
+
+			if tt.wantCode != "" {
+				var verr *domain.ValidationError
+				if !errors.As(err, &verr) || verr.Code != tt.wantCode {
+					t.Fatalf("err = %v, want validation error %q", err, tt.wantCode)
+				}
+				if !errors.Is(err, domain.ErrInvalidInput) {
+					t.Errorf("err = %v, want it to wrap ErrInvalidInput", err)
+				}
+				if len(r.created) != 0 {
+					t.Errorf("repository called for an invalid job")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v, want nil", err)
+			}
+			if len(r.created) != 1 {
+				t.Fatalf("repository got %d jobs, want 1", len(r.created))
+			}
+			if tt.check != nil {
+				tt.check(t, r.created[0])
+			}
+		})
+	}
+}
+
+func TestCreateRequiresID(t *testing.T) {
+	r := &fakeJobRepo{}
+	spec := validSpec()
+	spec.ID = " "
+
+	_, _, err := NewJobService(r, Policy{}).Create(context.Background(), spec)
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("err = %v, want ErrInvalidInput", err)
+	}
+	if len(r.created) != 0 {
+		t.Errorf("repository called for a job without ID")
+	}
+}
+
+func TestCreateManyKeepsInvalidItemsOut(t *testing.T) {
+	r := &fakeJobRepo{}
+	bad := validSpec()
+	bad.ID = "job-2"
+	bad.Priority = domain.MaxPriority + 1
+	good := validSpec()
+	good.ID = "job-3"
+
+	results := NewJobService(r, Policy{}).CreateMany(context.Background(), []domain.JobSpec{bad, good})
+
+	var verr *domain.ValidationError
+	if !errors.As(results[0].Err, &verr) || verr.Code != "invalid_priority" {
+		t.Errorf("results[0].Err = %v, want invalid_priority", results[0].Err)
+	}
+	if results[1].Err != nil || results[1].JobID != "job-3" {
+		t.Errorf("results[1] = %+v, want job-3 created", results[1])
+	}
+	if len(r.created) != 1 || r.created[0].ID != "job-3" {
+		t.Errorf("repository got %d jobs, want only job-3", len(r.created))
+	}
+}
+
+func TestNormalizeIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *string
+	}{
+		{in: "", want: nil},
+		{in: "   ", want: nil},
+		{in: "\t\n", want: nil},
+		{in: "abc", want: strPtr("abc")},
+		{in: "  abc  ", want: strPtr("abc")},
+		{in: " a b ", want: strPtr("a b")},
+	}
+	for _, tt := range tests {
+		got := NormalizeIdempotencyKey(tt.in)
+		switch {
+		case tt.want == nil && got != nil:
+			t.Errorf("NormalizeIdempotencyKey(%q) = %q, want nil", tt.in, *got)
+		case tt.want != nil && (got == nil || *got != *tt.want):
+			t.Errorf("NormalizeIdempotencyKey(%q) = %v, want %q", tt.in, got, *tt.want)
+		}
+	}
+}
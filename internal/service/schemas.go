@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"task-scheduler/internal/domain"
+	"task-scheduler/internal/jsonschema"
+	"task-scheduler/internal/repo"
+)
+
+// SchemaRegistry resolves the payload schema of each job type. Schemas set
+// through the API (stored by Repo) take precedence over those loaded from
+// the config file. Stored schemas are cached for TTL, so a change made by
+// another process is picked up within that time.
+type SchemaRegistry struct {
+	Repo repo.PayloadSchemaRepository
+	TTL  time.Duration
+
+	static map[string]compiledSchema
+
+	mu    sync.Mutex
+	cache map[string]cachedSchema
+}
+
+type compiledSchema struct {
+	raw    json.RawMessage
+	schema *jsonschema.Schema
+}
+
+// cachedSchema is a stored schema, or the fact there is none (schema nil).
+type cachedSchema struct {
+	schema  *jsonschema.Schema
+	expires time.Time
+}
+
+// NewSchemaRegistry compiles the config schemas (type -> schema document).
+func NewSchemaRegistry(r repo.PayloadSchemaRepository, static map[string]json.RawMessage, ttl time.Duration) (*SchemaRegistry, error) {
+	reg := &SchemaRegistry{
+		Repo:   r,
+		TTL:    ttl,
+		static: make(map[string]compiledSchema, len(static)),
+		cache:  map[string]cachedSchema{},
+	}
+	for jobType, raw := range static {
+		s, err := jsonschema.Compile(raw)
+		if err != nil {
+			return nil, fmt.Errorf("schema for %s: %w", jobType, err)
+		}
+		reg.static[jobType] = compiledSchema{raw: raw, schema: s}
+	}
+	return reg, nil
+}
+
+// LoadSchemaFile reads a JSON object mapping job types to their schemas.
+// An empty path yields no schemas.
+func LoadSchemaFile(path string) (map[string]json.RawMessage, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return out, nil
+}
+
+// Lookup returns the schema for jobType, or nil if it has none.
+func (r *SchemaRegistry) Lookup(ctx context.Context, jobType string) (*jsonschema.Schema, error) {
+	if r.Repo != nil {
+		r.mu.Lock()
+		c, ok := r.cache[jobType]
+		r.mu.Unlock()
+		if !ok || time.Now().After(c.expires) {
+			stored, err := r.Repo.GetPayloadSchema(ctx, jobType)
+			if err != nil {
+				return nil, err
+			}
+			c = cachedSchema{expires: time.Now().Add(r.TTL)}
+			if stored != nil {
+				if c.schema, err = jsonschema.Compile(stored.Schema); err != nil {
+					return nil, fmt.Errorf("stored schema for %s: %w", jobType, err)
+				}
+			}
+			r.mu.Lock()
+			r.cache[jobType] = c
+			r.mu.Unlock()
+		}
+		if c.schema != nil {
+			return c.schema, nil
+		}
+	}
+	if s, ok := r.static[jobType]; ok {
+		return s.schema, nil
+	}
+	return nil, nil
+}
+
+// Validate checks payload against the schema of jobType. A mismatch is a
+// *domain.ValidationError with one field error per violation, rooted at
+// "payload"; types without a schema accept any payload.
+func (r *SchemaRegistry) Validate(ctx context.Context, jobType string, payload json.RawMessage) error {
+	s, err := r.Lookup(ctx, jobType)
+	if err != nil || s == nil {
+		return err
+	}
+	violations := s.Validate(payload)
+	if len(violations) == 0 {
+		return nil
+	}
+	ve := &domain.ValidationError{
+		Code:    "payload_schema_mismatch",
+		Message: fmt.Sprintf("payload does not match the schema for %s", jobType),
+	}
+	for _, v := range violations {
+		field := "payload"
+		if v.Path != "" {
+			field += "." + v.Path
+		}
+		ve.Fields = append(ve.Fields, domain.FieldError{Field: field, Message: v.Message})
+	}
+	return ve
+}
+
+// List returns every effective schema, stored ones shadowing the config.
+func (r *SchemaRegistry) List(ctx context.Context) ([]domain.PayloadSchema, error) {
+	byType := map[string]domain.PayloadSchema{}
+	for jobType, s := range r.static {
+		byType[jobType] = domain.PayloadSchema{JobType: jobType, Schema: s.raw, Source: domain.SchemaSourceConfig}
+	}
+	if r.Repo != nil {
+		stored, err := r.Repo.ListPayloadSchemas(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stored {
+			byType[s.JobType] = s
+		}
+	}
+
+	out := make([]domain.PayloadSchema, 0, len(byType))
+	for _, s := range byType {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].JobType < out[j].JobType })
+	return out, nil
+}
+
+// Set stores the schema for jobType after checking that it compiles.
+func (r *SchemaRegistry) Set(ctx context.Context, jobType string, raw json.RawMessage) error {
+	jobType = strings.TrimSpace(jobType)
+	if jobType == "" || len(jobType) > maxTypeLen {
+		return domain.Invalid("invalid_type", "job_type", fmt.Sprintf("must be 1 to %d characters", maxTypeLen))
+	}
+	s, err := jsonschema.Compile(raw)
+	if err != nil {
+		return domain.Invalid("invalid_schema", "schema", err.Error())
+	}
+	if r.Repo == nil {
+		return errors.New("schema store not configured")
+	}
+	if err := r.Repo.SetPayloadSchema(ctx, domain.PayloadSchema{JobType: jobType, Schema: raw}); err != nil {
+		return err
+	}
+	r.remember(jobType, s)
+	return nil
+}
+
+// Delete removes the stored schema for jobType; a config schema for the
+// type, if any, applies again.
+func (r *SchemaRegistry) Delete(ctx context.Context, jobType string) error {
+	if r.Repo == nil {
+		return domain.ErrNotFound
+	}
+	if err := r.Repo.DeletePayloadSchema(ctx, jobType); err != nil {
+		return err
+	}
+	r.remember(jobType, nil)
+	return nil
+}
+
+func (r *SchemaRegistry) remember(jobType string, s *jsonschema.Schema) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache[jobType] = cachedSchema{schema: s, expires: time.Now().Add(r.TTL)}
+}
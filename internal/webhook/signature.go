@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderSignature = "X-Scheduler-Signature"
+	HeaderTimestamp = "X-Scheduler-Timestamp"
+	HeaderDelivery  = "X-Scheduler-Delivery"
+)
+
+// Sign returns the X-Scheduler-Signature value for body sent at ts.
+// The MAC covers "<unix ts>.<body>" so a captured request cannot be
+// replayed later with a fresh timestamp.
+func Sign(secret []byte, ts time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(ts.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a received signature and rejects timestamps further than
+// tolerance from now. Receivers should call this before trusting a payload.
+func Verify(secret []byte, signature, timestamp string, body []byte, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(strings.TrimSpace(timestamp), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q", timestamp)
+	}
+	ts := time.Unix(unix, 0)
+	if tolerance > 0 && (now.Sub(ts) > tolerance || ts.Sub(now) > tolerance) {
+		return fmt.Errorf("timestamp outside tolerance")
+	}
+
+	expected := Sign(secret, ts, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.TrimSpace(signature))) {
+		return fmt.Errorf("signature mismatch")
+	}
+	return nil
+}
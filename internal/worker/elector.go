@@ -0,0 +1,167 @@
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	mysqlrepo "task-scheduler/internal/repo/mysql"
+)
+
+// Elector picks one leader among the workers by holding a named lease, the
+// way jobs are held with locked_until: the leader renews it every TTL/3, and
+// when it stops, another worker takes over once the lease expires.
+//
+// ID is made unique to the process (see NewElector): the lease is granted
+// again to its current holder, so two workers sharing a WORKER_ID would
+// otherwise both lead.
+//
+// Tasks added with Go run only while this worker leads: they start when
+// leadership is gained and their context is cancelled when it is lost, and
+// the tasks have returned before OnLost is called. A task must return soon
+// after its context is cancelled: the next leader may already be starting.
+type Elector struct {
+	Leases *mysqlrepo.LeaseRepo
+	Name   string
+	ID     string
+	TTL    time.Duration
+
+	// OnGained and OnLost, if set, are called when this worker becomes or
+	// stops being the leader.
+	OnGained func()
+	OnLost   func()
+	Logger   *log.Logger
+
+	leader atomic.Bool
+	tasks  []leaderTask
+}
+
+type leaderTask struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+// NewElector returns an Elector campaigning as id, suffixed with the host
+// name, process ID and a random tag.
+func NewElector(leases *mysqlrepo.LeaseRepo, name, id string, ttl time.Duration, logger *log.Logger) *Elector {
+	if logger == nil {
+		logger = log.Default()
+	}
+	if ttl <= 0 {
+		ttl = 15 * time.Second
+	}
+	return &Elector{
+		Leases: leases,
+		Name:   name,
+		ID:     holderID(id),
+		TTL:    ttl,
+		Logger: logger,
+	}
+}
+
+// holderID returns id made unique to this process.
+func holderID(id string) string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	var tag [4]byte
+	_, _ = rand.Read(tag[:])
+	return fmt.Sprintf("%s/%s/%d/%s", id, host, os.Getpid(), hex.EncodeToString(tag[:]))
+}
+
+// Go adds a task to run while this worker leads. It must be called before
+// Run.
+func (e *Elector) Go(name string, run func(ctx context.Context)) {
+	e.tasks = append(e.tasks, leaderTask{name: name, run: run})
+}
+
+// IsLeader reports whether this worker currently leads.
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run campaigns for the lease until ctx is cancelled, then steps down and
+// releases it so that another worker takes over at once.
+func (e *Elector) Run(ctx context.Context) {
+	t := time.NewTicker(e.TTL / 3)
+	defer t.Stop()
+
+	var (
+		stop    context.CancelFunc
+		running sync.WaitGroup
+		renewed time.Time // last successful renewal while leading
+	)
+	stepDown := func(reason string) {
+		stop()
+		running.Wait()
+		e.leader.Store(false)
+		e.Logger.Printf("leader %s: %s stepped down: %s", e.Name, e.ID, reason)
+		if e.OnLost != nil {
+			e.OnLost()
+		}
+	}
+
+	for {
+		start := time.Now()
+		actx, cancel := context.WithTimeout(ctx, e.TTL/3)
+		ok, err := e.Leases.AcquireLease(actx, e.Name, e.ID, e.TTL)
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+		case err != nil:
+			e.Logger.Printf("leader %s lease error: %v", e.Name, err)
+			// Step down before the lease can expire unnoticed; renewals
+			// start at most TTL/3 apart.
+			if e.IsLeader() && time.Since(renewed) > e.TTL-e.TTL/3 {
+				stepDown("lease not renewed")
+			}
+		case ok && !e.IsLeader():
+			renewed = start
+			e.leader.Store(true)
+			e.Logger.Printf("leader %s: %s elected", e.Name, e.ID)
+			if e.OnGained != nil {
+				e.OnGained()
+			}
+			stop = e.startTasks(ctx, &running)
+		case ok:
+			renewed = start
+		case e.IsLeader():
+			stepDown("lease taken over")
+		}
+
+		select {
+		case <-ctx.Done():
+			if e.IsLeader() {
+				stepDown("shutting down")
+				rctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := e.Leases.ReleaseLease(rctx, e.Name, e.ID); err != nil {
+					e.Logger.Printf("leader %s: %v", e.Name, err)
+				}
+				cancel()
+			}
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// startTasks runs the leader tasks until the returned func is called.
+func (e *Elector) startTasks(ctx context.Context, running *sync.WaitGroup) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	for _, task := range e.tasks {
+		running.Add(1)
+		go func(task leaderTask) {
+			defer running.Done()
+			task.run(ctx)
+			e.Logger.Printf("leader %s: task %s stopped", e.Name, task.name)
+		}(task)
+	}
+	return cancel
+}
@@ -0,0 +1,225 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"task-scheduler/internal/domain"
+)
+
+// HandlerFunc executes one job. Returning an error fails the attempt, which
+// is retried with backoff until max_attempts.
+type HandlerFunc func(jc *JobContext) error
+
+// CompensationFunc undoes a completed step, given the output the step saved.
+// It runs after the job failed terminally and may be retried, so it must be
+// safe to repeat.
+type CompensationFunc func(jc *JobContext, output json.RawMessage) error
+
+// UpcastFunc migrates a payload from one version to the next.
+type UpcastFunc func(payload json.RawMessage) (json.RawMessage, error)
+
+// Registry maps job types to handlers. Types without a handler fall back to
+// the runner's simulated execution.
+type Registry struct {
+	mu            sync.RWMutex
+	handlers      map[string]HandlerFunc
+	compensations map[string]map[string]CompensationFunc // type -> step -> fn
+	upcasters     map[string]map[int]UpcastFunc          // type -> from version -> fn
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers:      map[string]HandlerFunc{},
+		compensations: map[string]map[string]CompensationFunc{},
+		upcasters:     map[string]map[int]UpcastFunc{},
+	}
+}
+
+// Register installs h for jobType, replacing any previous handler.
+func (r *Registry) Register(jobType string, h HandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[jobType] = h
+}
+
+func (r *Registry) Lookup(jobType string) (HandlerFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.handlers[jobType]
+	return h, ok
+}
+
+// Compensate declares how to undo step of jobType. Compensations are looked
+// up by name rather than captured by Step, because they may run on another
+// worker long after the handler returned.
+func (r *Registry) Compensate(jobType, step string, fn CompensationFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.compensations[jobType] == nil {
+		r.compensations[jobType] = map[string]CompensationFunc{}
+	}
+	r.compensations[jobType][step] = fn
+}
+
+func (r *Registry) compensation(jobType, step string) (CompensationFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.compensations[jobType][step]
+	return fn, ok
+}
+
+func (r *Registry) hasCompensations(jobType string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.compensations[jobType]) > 0
+}
+
+// Upcast declares how to migrate payloads of jobType from version from to
+// from+1. The current version of a type is one past its newest upcaster, and
+// jobs queued with an older payload are migrated step by step before their
+// handler runs, so the handler only ever sees the current shape.
+func (r *Registry) Upcast(jobType string, from int, fn UpcastFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.upcasters[jobType] == nil {
+		r.upcasters[jobType] = map[int]UpcastFunc{}
+	}
+	r.upcasters[jobType][from] = fn
+}
+
+// CurrentVersion is the payload version handlers of jobType expect.
+func (r *Registry) CurrentVersion(jobType string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	current := 1
+	for from := range r.upcasters[jobType] {
+		if from+1 > current {
+			current = from + 1
+		}
+	}
+	return current
+}
+
+// errPayloadTooNew marks a payload written for a newer deploy; another
+// worker, or this one after an upgrade, can still run it.
+var errPayloadTooNew = errors.New("payload version is newer than this worker")
+
+// upcast migrates payload from version to the current version of jobType.
+// Types without upcasters are passed through unchanged.
+func (r *Registry) upcast(jobType string, version int, payload json.RawMessage) (json.RawMessage, int, error) {
+	current := r.CurrentVersion(jobType)
+	if current == 1 {
+		return payload, version, nil
+	}
+	if version > current {
+		return nil, 0, fmt.Errorf("%w: got %d, current is %d", errPayloadTooNew, version, current)
+	}
+	for ; version < current; version++ {
+		r.mu.RLock()
+		fn, ok := r.upcasters[jobType][version]
+		r.mu.RUnlock()
+		if !ok {
+			return nil, 0, fmt.Errorf("no upcaster for %s payload version %d", jobType, version)
+		}
+		out, err := runUpcast(fn, payload)
+		if err != nil {
+			return nil, 0, fmt.Errorf("upcast %s payload from version %d: %w", jobType, version, err)
+		}
+		payload = out
+	}
+	return payload, version, nil
+}
+
+func runUpcast(fn UpcastFunc, payload json.RawMessage) (out json.RawMessage, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("upcaster panic: %v", p)
+		}
+	}()
+	return fn(payload)
+}
+
+// stepStore persists step outputs; implemented by mysqlrepo.JobRepo.
+type stepStore interface {
+	LoadSteps(ctx context.Context, jobID string) ([]domain.StepRecord, error)
+	SaveStep(ctx context.Context, jobID, step string, output json.RawMessage) (json.RawMessage, error)
+}
+
+// maxStepName keeps "step:"+name within job_executions.step_key.
+const maxStepName = 95
+
+// JobContext is passed to handlers. It carries the job and offers Step for
+// side effects that must not be repeated when the job is retried.
+type JobContext struct {
+	context.Context
+	Job Job
+
+	store   stepStore
+	records []domain.StepRecord // as loaded when the attempt started
+	steps   map[string]json.RawMessage
+}
+
+// StepFunc is the body of a step. Its result must be JSON-encodable.
+type StepFunc func(ctx context.Context) (any, error)
+
+// Step runs fn at most once per job: the first successful run's output is
+// saved, and any later call with the same name, in this attempt or a retry,
+// returns the saved output without calling fn. A failed step saves nothing
+// and runs again on the next attempt.
+//
+// Step names must be unique within a handler and stable across deploys. To
+// make a step undoable, declare a compensation with Registry.Compensate.
+func (c *JobContext) Step(name string, fn StepFunc) (json.RawMessage, error) {
+	if name == "" || len(name) > maxStepName {
+		return nil, fmt.Errorf("step name must be 1-%d bytes", maxStepName)
+	}
+	if out, ok := c.steps[name]; ok {
+		return out, nil
+	}
+
+	result, err := fn(c)
+	if err != nil {
+		return nil, fmt.Errorf("step %s: %w", name, err)
+	}
+	out, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("step %s: encode output: %w", name, err)
+	}
+
+	// If a concurrent run saved the step first, continue with its output.
+	saved, err := c.store.SaveStep(c, c.Job.ID, name, out)
+	if err != nil {
+		return nil, fmt.Errorf("step %s: %w", name, err)
+	}
+	c.steps[name] = saved
+	return saved, nil
+}
+
+// StepAs is Step with a typed result.
+func StepAs[T any](c *JobContext, name string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var v T
+	out, err := c.Step(name, func(ctx context.Context) (any, error) { return fn(ctx) })
+	if err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		return v, fmt.Errorf("step %s: decode output: %w", name, err)
+	}
+	return v, nil
+}
+
+func newJobContext(ctx context.Context, store stepStore, job Job) (*JobContext, error) {
+	records, err := store.LoadSteps(ctx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	steps := make(map[string]json.RawMessage, len(records))
+	for _, st := range records {
+		steps[st.Name] = st.Output
+	}
+	return &JobContext{Context: ctx, Job: job, store: store, records: records, steps: steps}, nil
+}
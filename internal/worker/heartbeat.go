@@ -2,17 +2,23 @@ package worker
 
 import (
 	"context"
+	"errors"
+	"log"
 	"time"
 
+	"task-scheduler/internal/domain"
 	mysqlrepo "task-scheduler/internal/repo/mysql"
 )
 
+// HeartbeatManager keeps the leases of running jobs alive, so that jobs
+// running longer than one lease are neither reclaimed nor reaped.
 type HeartbeatManager struct {
 	Repo     *mysqlrepo.JobRepo
 	WorkerID string
 	ExtendBy time.Duration
 	Interval time.Duration
 	Now      func() time.Time
+	Logger   *log.Logger
 }
 
 func NewHeartbeatManager(repo *mysqlrepo.JobRepo, workerID string, extendBy, interval time.Duration) *HeartbeatManager {
@@ -22,10 +28,13 @@ func NewHeartbeatManager(repo *mysqlrepo.JobRepo, workerID string, extendBy, int
 		ExtendBy: extendBy,
 		Interval: interval,
 		Now:      time.Now,
+		Logger:   log.Default(),
 	}
 }
 
-// Run periodically extends lease for a running job (call per job if you track running jobs).
+// Run extends the lease on jobID every Interval until ctx is cancelled or
+// the lease is lost; it then returns ctx.Err() or the domain.ErrConflict
+// from the repository. Other errors are logged and retried on the next tick.
 func (h *HeartbeatManager) Run(ctx context.Context, jobID string) error {
 	t := time.NewTicker(h.Interval)
 	defer t.Stop()
@@ -35,7 +44,14 @@ func (h *HeartbeatManager) Run(ctx context.Context, jobID string) error {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-t.C:
-			_ = h.Repo.Heartbeat(ctx, jobID, h.WorkerID, h.ExtendBy, h.Now())
+			err := h.Repo.Heartbeat(ctx, jobID, h.WorkerID, h.ExtendBy, h.Now())
+			switch {
+			case err == nil, ctx.Err() != nil:
+			case errors.Is(err, domain.ErrConflict):
+				return err
+			default:
+				h.Logger.Printf("job %s heartbeat error: %v", jobID, err)
+			}
 		}
 	}
 }
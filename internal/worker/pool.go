@@ -2,13 +2,50 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
+
+	"task-scheduler/internal/domain"
 )
 
 type Job struct {
-	ID          string
-	Attempts    int
-	MaxAttempts int
+	ID       string
+	TenantID string
+	Type     string
+	Queue    string
+	Payload  json.RawMessage
+	// PayloadRef is the blob-store key of an offloaded payload; Process
+	// loads it into Payload.
+	PayloadRef *string
+	// PayloadEncoding is the compression of a sealed or offloaded payload,
+	// undone by Process.
+	PayloadEncoding string
+	// PayloadVersion is the shape of Payload; handlers see the current one.
+	PayloadVersion int
+	Status         domain.JobStatus
+	Attempts       int
+	MaxAttempts    int
+
+	CompensationAttempts int
+}
+
+// JobFrom is the Job to process for a claimed row.
+func JobFrom(j domain.Job) Job {
+	return Job{
+		ID:              j.ID,
+		TenantID:        j.TenantID,
+		Type:            j.Type,
+		Queue:           j.Queue,
+		Payload:         j.Payload,
+		PayloadRef:      j.PayloadRef,
+		PayloadEncoding: j.PayloadEncoding,
+		PayloadVersion:  j.PayloadVersion,
+		Status:          j.Status,
+		Attempts:        j.Attempts,
+		MaxAttempts:     j.MaxAttempts,
+
+		CompensationAttempts: j.CompensationAttempts,
+	}
 }
 
 type Handler interface {
@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	mysqlrepo "task-scheduler/internal/repo/mysql"
+)
+
+// Reaper fails RUNNING jobs whose lease expired: their worker crashed or
+// hung. Claims would pick such jobs up again anyway, but without counting
+// the lost run, so a job that kills its worker would loop forever; and in a
+// paused queue it would stay RUNNING, holding its tenant's quota. The
+// reaper records the lost run like any failure: a retry with backoff, a
+// rollback for sagas, or a terminal failure once attempts are used up.
+//
+// With Limits set, it also prunes concurrency_keys rows unused for a day.
+type Reaper struct {
+	Repo     *mysqlrepo.JobRepo
+	Runner   *Runner
+	Limits   *mysqlrepo.ConcurrencyLimitRepo
+	WorkerID string
+	// Grace is how long after its lease ends a job counts as stuck.
+	Grace     time.Duration
+	Lease     time.Duration
+	BatchSize int
+	Logger    *log.Logger
+}
+
+func NewReaper(repo *mysqlrepo.JobRepo, runner *Runner, workerID string, logger *log.Logger) *Reaper {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Reaper{
+		Repo:      repo,
+		Runner:    runner,
+		WorkerID:  workerID,
+		Lease:     30 * time.Second,
+		BatchSize: 100,
+		Logger:    logger,
+	}
+}
+
+// Run reaps every interval until ctx is cancelled.
+func (r *Reaper) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			r.ReapOnce(ctx)
+		}
+	}
+}
+
+// ReapOnce fails one batch of stuck jobs and reports how many it took.
+func (r *Reaper) ReapOnce(ctx context.Context) int {
+	now := time.Now()
+	stuck, err := r.Repo.ClaimStuckJobs(ctx, r.WorkerID, now.Add(-r.Grace), now.Add(r.Lease), r.BatchSize)
+	if err != nil {
+		r.Logger.Printf("reaper claim error: %v", err)
+		return 0
+	}
+
+	for _, j := range stuck {
+		job := JobFrom(j)
+		// Compensations may need the payload. If it cannot be loaded now,
+		// the job is left for the next pass once this lease ends.
+		if err := r.Runner.loadPayload(ctx, &job); err != nil {
+			r.Logger.Printf("job %s reaper load payload error: %v", job.ID, err)
+			continue
+		}
+		r.Logger.Printf("job %s lease expired, reaping", job.ID)
+		r.Runner.fail(ctx, job, "lease expired: worker stopped", false)
+	}
+
+	if r.Limits != nil {
+		if _, err := r.Limits.PruneConcurrencyKeys(ctx, now.Add(-24*time.Hour), 1000); err != nil {
+			r.Logger.Printf("reaper prune error: %v", err)
+		}
+	}
+	return len(stuck)
+}
@@ -0,0 +1,300 @@
+package worker
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-scheduler/internal/domain"
+	mysqlrepo "task-scheduler/internal/repo/mysql"
+)
+
+// RetentionSweeper deletes finished jobs whose retention rule has expired,
+// in small batches so that no transaction locks many rows. It runs on the
+// leader only (see Elector). With ArchiveDir set, jobs are first appended
+// to a gzip-compressed NDJSON file there, one per sweep, and each batch
+// reaches the disk before it is deleted.
+type RetentionSweeper struct {
+	Jobs  *mysqlrepo.JobRepo
+	Repo  *mysqlrepo.RetentionRepo
+	Rules []domain.RetentionRule
+
+	ArchiveDir string
+	BatchSize  int
+	Pause      time.Duration // between batches
+	WorkerID   string
+	Logger     *log.Logger
+}
+
+func NewRetentionSweeper(jobs *mysqlrepo.JobRepo, repo *mysqlrepo.RetentionRepo, rules []domain.RetentionRule, workerID string, logger *log.Logger) *RetentionSweeper {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &RetentionSweeper{
+		Jobs:      jobs,
+		Repo:      repo,
+		Rules:     rules,
+		BatchSize: 500,
+		Pause:     200 * time.Millisecond,
+		WorkerID:  workerID,
+		Logger:    logger,
+	}
+}
+
+// Run sweeps at once, then every interval until ctx is cancelled.
+func (s *RetentionSweeper) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		s.SweepOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// SweepOnce runs one sweep and records it for GET /admin/retention. A
+// cancelled ctx stops it between batches.
+func (s *RetentionSweeper) SweepOnce(ctx context.Context) {
+	run := domain.RetentionRun{WorkerID: s.WorkerID, StartedAt: time.Now()}
+	var arc *archive
+	err := s.sweep(ctx, &run, &arc)
+	if arc != nil {
+		if cerr := arc.close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	run.FinishedAt = time.Now()
+	if err != nil {
+		run.Error = err.Error()
+		s.Logger.Printf("retention sweep error: %v", err)
+	}
+	if err := s.Repo.SaveRetentionRun(context.WithoutCancel(ctx), run); err != nil {
+		s.Logger.Printf("retention run not recorded: %v", err)
+	}
+	s.Logger.Printf("retention sweep deleted=%d archived=%d (%s)", run.Deleted, run.Archived, run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond))
+}
+
+func (s *RetentionSweeper) sweep(ctx context.Context, run *domain.RetentionRun, arc **archive) error {
+	// Types with a rule of their own for a status are left out of that
+	// status's default rule.
+	overridden := map[domain.JobStatus][]string{}
+	for _, rule := range s.Rules {
+		if rule.Type != "" {
+			overridden[rule.Status] = append(overridden[rule.Status], rule.Type)
+		}
+	}
+
+	for _, rule := range s.Rules {
+		stats := domain.RetentionRuleStats{Status: rule.Status, Type: rule.Type, TTL: formatTTL(rule.TTL)}
+		err := s.sweepRule(ctx, rule, overridden[rule.Status], &stats, run, arc)
+		run.Rules = append(run.Rules, stats)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RetentionSweeper) sweepRule(ctx context.Context, rule domain.RetentionRule, except []string, stats *domain.RetentionRuleStats, run *domain.RetentionRun, arc **archive) error {
+	if rule.TTL <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-rule.TTL)
+	for {
+		ids, err := s.Repo.ExpiredJobs(ctx, rule, except, cutoff, s.BatchSize)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		if s.ArchiveDir != "" {
+			recs, err := s.Repo.ArchiveRecords(ctx, ids)
+			if err != nil {
+				return err
+			}
+			if *arc == nil {
+				if *arc, err = openArchive(s.ArchiveDir, run.StartedAt); err != nil {
+					return err
+				}
+				run.ArchiveFile = (*arc).path
+			}
+			if err := (*arc).write(recs); err != nil {
+				return err
+			}
+			run.Archived += len(recs)
+		}
+
+		n, err := s.Jobs.PurgeJobs(ctx, "", ids)
+		if err != nil {
+			return err
+		}
+		stats.Deleted += n
+		run.Deleted += n
+		if n == 0 || len(ids) < s.BatchSize {
+			// n == 0: every job was retried since it was found.
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.Pause):
+		}
+	}
+}
+
+// archive is the gzip-compressed NDJSON file of one sweep.
+type archive struct {
+	path string
+	f    *os.File
+	zw   *gzip.Writer
+}
+
+func openArchive(dir string, started time.Time) (*archive, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("archive dir: %w", err)
+	}
+	path := filepath.Join(dir, "jobs-"+started.UTC().Format("20060102T150405Z")+".ndjson.gz")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("create archive: %w", err)
+	}
+	return &archive{path: path, f: f, zw: gzip.NewWriter(f)}, nil
+}
+
+// write appends recs and syncs them to disk, so that they are safe before
+// the jobs are deleted. A crash leaves a truncated but readable file.
+func (a *archive) write(recs []domain.ArchivedJob) error {
+	enc := json.NewEncoder(a.zw)
+	for _, rec := range recs {
+		if err := enc.Encode(rec); err != nil {
+			return fmt.Errorf("write archive: %w", err)
+		}
+	}
+	if err := a.zw.Flush(); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	if err := a.f.Sync(); err != nil {
+		return fmt.Errorf("write archive: %w", err)
+	}
+	return nil
+}
+
+func (a *archive) close() error {
+	if err := a.zw.Close(); err != nil {
+		a.f.Close()
+		return fmt.Errorf("close archive: %w", err)
+	}
+	if err := a.f.Sync(); err != nil {
+		a.f.Close()
+		return fmt.Errorf("close archive: %w", err)
+	}
+	return a.f.Close()
+}
+
+// retentionFile is the RETENTION_FILE format: TTLs by status, with
+// per-type overrides.
+//
+//	{
+//	  "default": {"SUCCESS": "7d", "FAILED": "30d", "CANCELLED": "7d"},
+//	  "types": {"report": {"SUCCESS": "90d"}, "audit": {"FAILED": "never"}}
+//	}
+type retentionFile struct {
+	Default map[domain.JobStatus]string            `json:"default"`
+	Types   map[string]map[domain.JobStatus]string `json:"types"`
+}
+
+// LoadRetentionRules reads a retention policy file. TTLs are Go durations
+// ("36h") or whole days ("7d"); "never" keeps the jobs. Only finished
+// statuses may be listed; jobs in statuses without a rule are kept.
+func LoadRetentionRules(path string) ([]domain.RetentionRule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f retentionFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var rules []domain.RetentionRule
+	add := func(jobType string, ttls map[domain.JobStatus]string) error {
+		for status, v := range ttls {
+			status = domain.JobStatus(strings.ToUpper(string(status)))
+			if !status.Terminal() {
+				return fmt.Errorf("%s: %s is not a finished status", path, status)
+			}
+			ttl, err := parseTTL(v)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", path, status, err)
+			}
+			rules = append(rules, domain.RetentionRule{Status: status, Type: jobType, TTL: ttl})
+		}
+		return nil
+	}
+	if err := add("", f.Default); err != nil {
+		return nil, err
+	}
+	for jobType, ttls := range f.Types {
+		if jobType == "" {
+			return nil, fmt.Errorf("%s: empty job type", path)
+		}
+		if err := add(jobType, ttls); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Status != rules[j].Status {
+			return rules[i].Status < rules[j].Status
+		}
+		return rules[i].Type < rules[j].Type
+	})
+	return rules, nil
+}
+
+func parseTTL(v string) (time.Duration, error) {
+	v = strings.TrimSpace(v)
+	if v == "never" {
+		return 0, nil
+	}
+	var ttl time.Duration
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid TTL %q", v)
+		}
+		ttl = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid TTL %q", v)
+		}
+		ttl = d
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("TTL %q must be positive (or \"never\")", v)
+	}
+	return ttl, nil
+}
+
+func formatTTL(ttl time.Duration) string {
+	if ttl <= 0 {
+		return "never"
+	}
+	if ttl%(24*time.Hour) == 0 {
+		return strconv.Itoa(int(ttl/(24*time.Hour))) + "d"
+	}
+	return ttl.String()
+}
@@ -2,20 +2,52 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"math/rand"
 	"time"
 
+	"task-scheduler/internal/blob"
+	"task-scheduler/internal/codec"
+	"task-scheduler/internal/domain"
+	"task-scheduler/internal/keyring"
 	mysqlrepo "task-scheduler/internal/repo/mysql"
 )
 
 // Runner executes jobs and applies retry/backoff + exactly-once success guard.
 type Runner struct {
 	Repo      *mysqlrepo.JobRepo
+	Handlers  *Registry
 	Backoff   BackoffConfig
-	FailRate  float64 // demo-only (failure injection)
+	FailRate  float64 // demo-only (failure injection) for types without a handler
 	Logger    *log.Logger
 	StepKeyOK string // step key used for success marker
+
+	// CompensationMaxAttempts bounds the runs of a saga rollback before the
+	// job ends COMPENSATION_FAILED.
+	CompensationMaxAttempts int
+
+	// Schemas, when set, checks payloads before the handler runs. A payload
+	// that does not match its type's schema fails the job without retries.
+	Schemas PayloadValidator
+
+	// Keyring opens payloads encrypted at rest before handlers see them.
+	Keyring *keyring.Keyring
+
+	// Blobs holds payloads offloaded from the jobs table.
+	Blobs blob.Store
+
+	// Heartbeat, when set, keeps each job's lease alive while it runs. If
+	// the lease is lost anyway, the job's context is cancelled.
+	Heartbeat *HeartbeatManager
+}
+
+// PayloadValidator checks a payload against its job type's schema; a
+// mismatch wraps domain.ErrInvalidInput. Implemented by service.SchemaRegistry.
+type PayloadValidator interface {
+	Validate(ctx context.Context, jobType string, payload json.RawMessage) error
 }
 
 func NewRunner(repo *mysqlrepo.JobRepo, backoff BackoffConfig, failRate float64, logger *log.Logger) *Runner {
@@ -24,80 +56,306 @@ func NewRunner(repo *mysqlrepo.JobRepo, backoff BackoffConfig, failRate float64,
 	}
 	return &Runner{
 		Repo:      repo,
+		Handlers:  NewRegistry(),
 		Backoff:   backoff,
 		FailRate:  failRate,
 		Logger:    logger,
 		StepKeyOK: "execute_success",
+
+		CompensationMaxAttempts: 5,
 	}
 }
 
 // Process implements Pool Handler interface.
 func (r *Runner) Process(ctx context.Context, job Job) {
 	start := time.Now()
+	if r.Heartbeat != nil {
+		var stop context.CancelFunc
+		ctx, stop = r.keepLease(ctx, job.ID)
+		defer stop()
+	}
 
-	// Simulated work (replace later with handler registry by job type)
-	failed := rand.Float64() < r.FailRate
+	if err := r.loadPayload(ctx, &job); err != nil {
+		if job.Status == domain.StatusCompensating {
+			r.retryCompensation(ctx, job, err.Error())
+		} else {
+			r.fail(ctx, job, err.Error(), false)
+		}
+		return
+	}
 
-	if !failed {
-		// Exactly-once marker for completed side-effect.
-		inserted, err := r.Repo.RecordStepOnce(ctx, job.ID, r.StepKeyOK, nil)
-		if err != nil {
-			r.Logger.Printf("job %s RecordStepOnce error: %v", job.ID, err)
-			r.scheduleRetry(ctx, job, "record-step failed")
+	if job.Status == domain.StatusCompensating {
+		r.compensate(ctx, job, nil)
+		return
+	}
+
+	payload, version, err := r.Handlers.upcast(job.Type, job.PayloadVersion, job.Payload)
+	if err != nil {
+		// A broken migration fails every retry the same way.
+		r.fail(ctx, job, err.Error(), !errors.Is(err, errPayloadTooNew))
+		return
+	}
+	job.Payload, job.PayloadVersion = payload, version
+
+	// Schemas describe the current payload version, so they are checked
+	// after upcasting.
+	if r.Schemas != nil {
+		if err := r.Schemas.Validate(ctx, job.Type, job.Payload); err != nil {
+			// A mismatch would fail every retry the same way; a lookup
+			// error is worth retrying.
+			r.fail(ctx, job, schemaError(err), errors.Is(err, domain.ErrInvalidInput))
 			return
 		}
+	}
 
-		if err := r.Repo.MarkSuccess(ctx, job.ID, time.Now()); err != nil {
-			r.Logger.Printf("job %s MarkSuccess error: %v", job.ID, err)
-			return
+	if err := r.execute(ctx, job); err != nil {
+		r.fail(ctx, job, err.Error(), false)
+		return
+	}
+
+	// Exactly-once marker for completed side-effect.
+	inserted, err := r.Repo.RecordStepOnce(ctx, job.ID, r.StepKeyOK, nil)
+	if err != nil {
+		r.Logger.Printf("job %s RecordStepOnce error: %v", job.ID, err)
+		r.fail(ctx, job, "record-step failed", false)
+		return
+	}
+
+	if err := r.Repo.MarkSuccess(ctx, job.ID, time.Now()); err != nil {
+		r.Logger.Printf("job %s MarkSuccess error: %v", job.ID, err)
+		return
+	}
+
+	if !inserted {
+		r.Logger.Printf("job %s SUCCESS (idempotent replay) (%s)", job.ID, time.Since(start))
+		return
+	}
+
+	r.Logger.Printf("job %s SUCCESS (%s)", job.ID, time.Since(start))
+}
+
+// keepLease heartbeats jobID until the returned func is called. The
+// returned context is cancelled if the lease is lost, so that the handler
+// stops and its outcome is not recorded over another worker's run.
+func (r *Runner) keepLease(ctx context.Context, jobID string) (context.Context, context.CancelFunc) {
+	jobCtx, cancelJob := context.WithCancel(ctx)
+	hbCtx, stopHeartbeat := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := r.Heartbeat.Run(hbCtx, jobID); errors.Is(err, domain.ErrConflict) {
+			r.Logger.Printf("job %s stopped: %v", jobID, err)
+			cancelJob()
 		}
+	}()
+	return jobCtx, func() {
+		stopHeartbeat()
+		<-done
+		cancelJob()
+	}
+}
 
-		if !inserted {
-			r.Logger.Printf("job %s SUCCESS (idempotent replay) (%s)", job.ID, time.Since(start))
-			return
+// loadPayload fetches an offloaded payload from the blob store, opens it and
+// decompresses it.
+func (r *Runner) loadPayload(ctx context.Context, job *Job) error {
+	if job.PayloadRef != nil {
+		if r.Blobs == nil {
+			return errors.New("payload is offloaded but this worker has no blob store")
+		}
+		raw, err := r.Blobs.Get(ctx, *job.PayloadRef)
+		if err != nil {
+			return fmt.Errorf("load payload blob: %w", err)
 		}
+		job.Payload = raw
+	}
+	if err := r.openPayload(job); err != nil {
+		return err
+	}
+	payload, err := codec.Decode(job.PayloadEncoding, job.Payload)
+	if err != nil {
+		return err
+	}
+	job.Payload, job.PayloadEncoding = payload, codec.Identity
+	return nil
+}
 
-		r.Logger.Printf("job %s SUCCESS (%s)", job.ID, time.Since(start))
-		return
+// openPayload decrypts a payload sealed at rest. Plaintext payloads, from
+// before encryption was turned on, are left as they are.
+func (r *Runner) openPayload(job *Job) error {
+	if !keyring.Sealed(job.Payload) {
+		return nil
+	}
+	if r.Keyring == nil {
+		return errors.New("payload is encrypted but this worker has no keyring")
+	}
+	payload, err := r.Keyring.Open(job.Payload, job.ID)
+	if err != nil {
+		return err
+	}
+	job.Payload = payload
+	return nil
+}
+
+// execute runs the registered handler for job.Type, or simulates work for
+// types without one.
+func (r *Runner) execute(ctx context.Context, job Job) (err error) {
+	h, ok := r.Handlers.Lookup(job.Type)
+	if !ok {
+		if rand.Float64() < r.FailRate {
+			return errors.New("simulated failure")
+		}
+		return nil
+	}
+
+	jc, err := newJobContext(ctx, r.Repo, job)
+	if err != nil {
+		return fmt.Errorf("load steps: %w", err)
 	}
 
-	// failure path
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("handler panic: %v", p)
+		}
+	}()
+	return h(jc)
+}
+
+// fail records a failed attempt: a retry with backoff, or a terminal failure
+// once max_attempts is reached or the failure is permanent.
+func (r *Runner) fail(ctx context.Context, job Job, msg string, permanent bool) {
 	nextAttempts := job.Attempts + 1
-	terminal := nextAttempts >= job.MaxAttempts
+	terminal := permanent || nextAttempts >= job.MaxAttempts
 
 	if terminal {
-		err := r.Repo.MarkFailure(ctx, job.ID, nextAttempts, nil, "simulated failure", true, ptrTime(time.Now()))
+		if jc := r.undoable(ctx, job); jc != nil {
+			if err := r.Repo.MarkCompensating(ctx, job.ID, nextAttempts, msg); err != nil {
+				r.Logger.Printf("job %s MarkCompensating error: %v", job.ID, err)
+				return
+			}
+			r.Logger.Printf("job %s COMPENSATING attempts=%d/%d: %s", job.ID, nextAttempts, job.MaxAttempts, msg)
+			job.Status, job.Attempts = domain.StatusCompensating, nextAttempts
+			r.compensate(ctx, job, jc)
+			return
+		}
+
+		err := r.Repo.MarkFailure(ctx, job.ID, nextAttempts, nil, msg, true, ptrTime(time.Now()))
 		if err != nil {
 			r.Logger.Printf("job %s MarkFailure(terminal) error: %v", job.ID, err)
 			return
 		}
-		r.Logger.Printf("job %s FAILED terminal attempts=%d/%d", job.ID, nextAttempts, job.MaxAttempts)
+		r.Logger.Printf("job %s FAILED terminal attempts=%d/%d: %s", job.ID, nextAttempts, job.MaxAttempts, msg)
 		return
 	}
 
 	delay := r.Backoff.Next(nextAttempts)
 	nextRun := time.Now().Add(delay)
 
-	err := r.Repo.MarkFailure(ctx, job.ID, nextAttempts, &nextRun, "simulated failure", false, nil)
+	err := r.Repo.MarkFailure(ctx, job.ID, nextAttempts, &nextRun, msg, false, nil)
 	if err != nil {
 		r.Logger.Printf("job %s MarkFailure(retry) error: %v", job.ID, err)
 		return
 	}
-	r.Logger.Printf("job %s RETRY scheduled attempts=%d/%d next_in=%s", job.ID, nextAttempts, job.MaxAttempts, delay)
+	r.Logger.Printf("job %s RETRY scheduled attempts=%d/%d next_in=%s: %s", job.ID, nextAttempts, job.MaxAttempts, delay, msg)
 }
 
-func (r *Runner) scheduleRetry(ctx context.Context, job Job, msg string) {
-	nextAttempts := job.Attempts + 1
-	terminal := nextAttempts >= job.MaxAttempts
+// undoable returns a context with the job's steps if any completed step has
+// a compensation to run, or nil if the job can simply fail.
+func (r *Runner) undoable(ctx context.Context, job Job) *JobContext {
+	if !r.Handlers.hasCompensations(job.Type) {
+		return nil
+	}
+	jc, err := newJobContext(ctx, r.Repo, job)
+	if err != nil {
+		// Compensate anyway; the steps are loaded again on the next run.
+		r.Logger.Printf("job %s load steps error: %v", job.ID, err)
+		return &JobContext{Context: ctx, Job: job}
+	}
+	for _, st := range jc.records {
+		if _, ok := r.Handlers.compensation(job.Type, st.Name); ok && !st.Compensated {
+			return jc
+		}
+	}
+	return nil
+}
 
-	if terminal {
-		_ = r.Repo.MarkFailure(ctx, job.ID, nextAttempts, nil, msg, true, ptrTime(time.Now()))
+// compensate runs the compensations of job's completed steps, newest first.
+// Steps already compensated by an earlier run are skipped, so a failed
+// compensation is retried on its own without repeating the others.
+func (r *Runner) compensate(ctx context.Context, job Job, jc *JobContext) {
+	if jc == nil || jc.store == nil {
+		var err error
+		if jc, err = newJobContext(ctx, r.Repo, job); err != nil {
+			r.retryCompensation(ctx, job, fmt.Sprintf("load steps: %v", err))
+			return
+		}
+	}
+
+	for i := len(jc.records) - 1; i >= 0; i-- {
+		st := jc.records[i]
+		fn, ok := r.Handlers.compensation(job.Type, st.Name)
+		if !ok || st.Compensated {
+			continue
+		}
+		if err := runCompensation(jc, fn, st.Output); err != nil {
+			r.retryCompensation(ctx, job, fmt.Sprintf("compensate %s: %v", st.Name, err))
+			return
+		}
+		if err := r.Repo.RecordCompensation(ctx, job.ID, st.Name); err != nil {
+			r.retryCompensation(ctx, job, fmt.Sprintf("record compensation %s: %v", st.Name, err))
+			return
+		}
+	}
+
+	if err := r.Repo.FinishCompensation(ctx, job.ID, job.CompensationAttempts, false, nil, time.Now()); err != nil {
+		r.Logger.Printf("job %s FinishCompensation error: %v", job.ID, err)
 		return
 	}
+	r.Logger.Printf("job %s COMPENSATED", job.ID)
+}
 
-	delay := r.Backoff.Next(nextAttempts)
-	nextRun := time.Now().Add(delay)
-	_ = r.Repo.MarkFailure(ctx, job.ID, nextAttempts, &nextRun, msg, false, nil)
+func runCompensation(jc *JobContext, fn CompensationFunc, output []byte) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("compensation panic: %v", p)
+		}
+	}()
+	return fn(jc, output)
+}
+
+func (r *Runner) retryCompensation(ctx context.Context, job Job, msg string) {
+	next := job.CompensationAttempts + 1
+	if next >= r.CompensationMaxAttempts {
+		if err := r.Repo.FinishCompensation(ctx, job.ID, next, true, &msg, time.Now()); err != nil {
+			r.Logger.Printf("job %s FinishCompensation error: %v", job.ID, err)
+			return
+		}
+		r.Logger.Printf("job %s COMPENSATION_FAILED attempts=%d/%d: %s", job.ID, next, r.CompensationMaxAttempts, msg)
+		return
+	}
+
+	delay := r.Backoff.Next(next)
+	if err := r.Repo.MarkCompensationRetry(ctx, job.ID, next, time.Now().Add(delay), msg); err != nil {
+		r.Logger.Printf("job %s MarkCompensationRetry error: %v", job.ID, err)
+		return
+	}
+	r.Logger.Printf("job %s COMPENSATION RETRY attempts=%d/%d next_in=%s: %s", job.ID, next, r.CompensationMaxAttempts, delay, msg)
+}
+
+// schemaError flattens a schema mismatch into the job's last error.
+func schemaError(err error) string {
+	var ve *domain.ValidationError
+	if !errors.As(err, &ve) {
+		return fmt.Sprintf("schema lookup: %v", err)
+	}
+	msg := ve.Message
+	for i, f := range ve.Fields {
+		if i == 5 {
+			msg += fmt.Sprintf("; and %d more", len(ve.Fields)-i)
+			break
+		}
+		msg += "; " + f.Field + " " + f.Message
+	}
+	return msg
 }
 
 func ptrTime(t time.Time) *time.Time { return &t }
@@ -0,0 +1,156 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"task-scheduler/internal/domain"
+	mysqlrepo "task-scheduler/internal/repo/mysql"
+	"task-scheduler/internal/webhook"
+)
+
+// WebhookDispatcher drains the webhook_deliveries outbox, POSTing signed
+// notifications and retrying failures with the same BackoffConfig as jobs.
+type WebhookDispatcher struct {
+	Repo        *mysqlrepo.WebhookRepo
+	Client      *http.Client
+	Secret      []byte
+	Backoff     BackoffConfig
+	MaxAttempts int
+	WorkerID    string
+	// Lease is renewed before each attempt and must outlast Client.Timeout.
+	Lease     time.Duration
+	BatchSize int
+	Logger    *log.Logger
+}
+
+func NewWebhookDispatcher(repo *mysqlrepo.WebhookRepo, secret []byte, backoff BackoffConfig, maxAttempts int, workerID string, logger *log.Logger) *WebhookDispatcher {
+	if logger == nil {
+		logger = log.Default()
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+	return &WebhookDispatcher{
+		Repo:        repo,
+		Client:      webhook.NewClient(10*time.Second, false),
+		Secret:      secret,
+		Backoff:     backoff,
+		MaxAttempts: maxAttempts,
+		WorkerID:    workerID,
+		Lease:       30 * time.Second,
+		BatchSize:   20,
+		Logger:      logger,
+	}
+}
+
+// Run polls the outbox every interval until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			d.DispatchOnce(ctx)
+		}
+	}
+}
+
+// DispatchOnce claims and attempts one batch of due deliveries.
+func (d *WebhookDispatcher) DispatchOnce(ctx context.Context) {
+	deliveries, err := d.Repo.ClaimDeliveries(ctx, d.WorkerID, d.BatchSize, d.Lease, time.Now())
+	if err != nil {
+		d.Logger.Printf("webhook claim error: %v", err)
+		return
+	}
+
+	for _, del := range deliveries {
+		d.deliver(ctx, del)
+	}
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, del domain.WebhookDelivery) {
+	attempts := del.Attempts + 1
+
+	// The batch is posted in sequence, so the claim's lease may be nearly
+	// spent by now: renew it to cover this attempt, and leave the delivery
+	// alone if another worker has taken it over.
+	ok, err := d.Repo.RenewDelivery(ctx, del.ID, d.WorkerID, time.Now().Add(d.Lease))
+	if err != nil {
+		d.Logger.Printf("webhook %d renew error: %v", del.ID, err)
+		return
+	}
+	if !ok {
+		d.Logger.Printf("webhook %d lease lost, skipping", del.ID)
+		return
+	}
+
+	code, err := d.post(ctx, del)
+	if err == nil {
+		if err := d.Repo.MarkDelivered(ctx, del.ID, d.WorkerID, code, attempts, time.Now()); err != nil {
+			d.Logger.Printf("webhook %d MarkDelivered error: %v", del.ID, err)
+			return
+		}
+		d.Logger.Printf("webhook %d delivered job=%s status=%d", del.ID, del.JobID, code)
+		return
+	}
+
+	var codePtr *int
+	if code != 0 {
+		codePtr = &code
+	}
+
+	if attempts >= d.MaxAttempts {
+		if err := d.Repo.MarkDeliveryFailure(ctx, del.ID, d.WorkerID, attempts, codePtr, err.Error(), nil, true); err != nil {
+			d.Logger.Printf("webhook %d MarkDeliveryFailure(terminal) error: %v", del.ID, err)
+			return
+		}
+		d.Logger.Printf("webhook %d FAILED terminal job=%s attempts=%d/%d: %v", del.ID, del.JobID, attempts, d.MaxAttempts, err)
+		return
+	}
+
+	delay := d.Backoff.Next(attempts)
+	next := time.Now().Add(delay)
+	if err := d.Repo.MarkDeliveryFailure(ctx, del.ID, d.WorkerID, attempts, codePtr, err.Error(), &next, false); err != nil {
+		d.Logger.Printf("webhook %d MarkDeliveryFailure(retry) error: %v", del.ID, err)
+		return
+	}
+	d.Logger.Printf("webhook %d RETRY job=%s attempts=%d/%d next_in=%s: %v", del.ID, del.JobID, attempts, d.MaxAttempts, delay, err)
+}
+
+// post sends one attempt. Any non-2xx response is an error; the status code
+// is returned alongside so it can be recorded.
+func (d *WebhookDispatcher) post(ctx context.Context, del domain.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, del.URL, bytes.NewReader(del.Body))
+	if err != nil {
+		return 0, fmt.Errorf("build request: %w", err)
+	}
+
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "task-scheduler-webhook/1")
+	req.Header.Set(webhook.HeaderDelivery, strconv.FormatInt(del.ID, 10))
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(d.Secret, now, del.Body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
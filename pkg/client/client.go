@@ -0,0 +1,449 @@
+// Package client is a typed Go client for the scheduler's HTTP API.
+//
+//	c := client.New("http://localhost:8086", client.WithAPIKey(os.Getenv("API_KEY")))
+//	job, err := c.Create(ctx, client.CreateJobRequest{
+//		Type:           "send_email",
+//		Payload:        map[string]any{"to": "a@example.com"},
+//		IdempotencyKey: "welcome-42",
+//	})
+//	...
+//	job, err = c.Wait(ctx, job.ID, time.Second)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-scheduler/internal/domain"
+)
+
+type (
+	Job       = domain.Job
+	JobStatus = domain.JobStatus
+)
+
+// Errors that API errors unwrap to, for use with errors.Is.
+var (
+	ErrInvalidInput     = domain.ErrInvalidInput
+	ErrNotFound         = domain.ErrNotFound
+	ErrConflict         = domain.ErrConflict
+	ErrQuotaExceeded    = domain.ErrQuotaExceeded
+	ErrDependencyFailed = domain.ErrDependencyFailed
+)
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+type Option func(*Client)
+
+// WithAPIKey sends key as a bearer token on every request.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithRetries sets how many times a request is retried after a transient
+// failure (network errors, 429, 502, 503 and 504), with exponential backoff
+// between min and max. Defaults to 3 retries, 200ms to 5s.
+func WithRetries(n int, min, max time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = n
+		c.minBackoff = min
+		c.maxBackoff = max
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		maxRetries: 3,
+		minBackoff: 200 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CreateJobRequest mirrors the body of POST /jobs. Payload is marshalled
+// as JSON unless it is already a json.RawMessage.
+type CreateJobRequest struct {
+	Type    string
+	Queue   string
+	Payload any
+	// PayloadVersion is the shape of Payload; 0 means 1.
+	PayloadVersion int
+	MaxAttempts    int
+	Priority       int
+	CallbackURL    string
+
+	// Delay postpones the first run; RunAt sets it explicitly. Use at most one.
+	Delay time.Duration
+	RunAt *time.Time
+
+	ConcurrencyKey  string
+	DependsOn       []string
+	OnParentFailure string
+
+	// IdempotencyKey is sent as the Idempotency-Key header. Creates are only
+	// retried on transient failures when it is set.
+	IdempotencyKey string
+}
+
+// Create submits a job. If IdempotencyKey matches an identical earlier
+// request, that job is returned instead.
+func (c *Client) Create(ctx context.Context, req CreateJobRequest) (*Job, error) {
+	payload, ok := req.Payload.(json.RawMessage)
+	if !ok {
+		var err error
+		if payload, err = json.Marshal(req.Payload); err != nil {
+			return nil, fmt.Errorf("%w: marshal payload: %v", ErrInvalidInput, err)
+		}
+	}
+
+	body := map[string]any{
+		"type":    req.Type,
+		"payload": payload,
+	}
+	if req.Queue != "" {
+		body["queue"] = req.Queue
+	}
+	if req.PayloadVersion > 0 {
+		body["payload_version"] = req.PayloadVersion
+	}
+	if req.MaxAttempts > 0 {
+		body["max_attempts"] = req.MaxAttempts
+	}
+	if req.Priority != 0 {
+		body["priority"] = req.Priority
+	}
+	if req.CallbackURL != "" {
+		body["callback_url"] = req.CallbackURL
+	}
+	if req.Delay > 0 {
+		// The API takes whole seconds; round up so the job never runs early.
+		body["delay_seconds"] = int((req.Delay + time.Second - 1) / time.Second)
+	}
+	if req.RunAt != nil {
+		body["run_at"] = req.RunAt.UTC()
+	}
+	if req.ConcurrencyKey != "" {
+		body["concurrency_key"] = req.ConcurrencyKey
+	}
+	if len(req.DependsOn) > 0 {
+		body["depends_on"] = req.DependsOn
+	}
+	if req.OnParentFailure != "" {
+		body["on_parent_failure"] = req.OnParentFailure
+	}
+
+	header := http.Header{}
+	if req.IdempotencyKey != "" {
+		header.Set("Idempotency-Key", req.IdempotencyKey)
+	}
+
+	var job Job
+	err := c.do(ctx, http.MethodPost, "/jobs", header, body, req.IdempotencyKey != "", &job)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Get fetches a job by ID.
+func (c *Client) Get(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, nil, true, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListOptions filters List; zero values match everything.
+type ListOptions struct {
+	Status JobStatus
+	Type   string
+	Queue  string
+	Limit  int
+	Cursor string
+}
+
+// JobPage is one page of List. NextCursor is empty on the last page.
+type JobPage struct {
+	Jobs       []Job  `json:"jobs"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// List returns jobs newest first. Pass NextCursor as ListOptions.Cursor to
+// fetch the following page.
+func (c *Client) List(ctx context.Context, opts ListOptions) (*JobPage, error) {
+	q := url.Values{}
+	if opts.Status != "" {
+		q.Set("status", string(opts.Status))
+	}
+	if opts.Type != "" {
+		q.Set("type", opts.Type)
+	}
+	if opts.Queue != "" {
+		q.Set("queue", opts.Queue)
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Cursor != "" {
+		q.Set("cursor", opts.Cursor)
+	}
+	path := "/jobs"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	var page JobPage
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, true, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// Cancel cancels a pending, blocked or running job. Finished jobs yield
+// an error wrapping ErrConflict.
+func (c *Client) Cancel(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodPost, "/jobs/"+url.PathEscape(id)+"/cancel", nil, nil, false, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Retry requeues a FAILED or CANCELLED job with fresh attempts.
+func (c *Client) Retry(ctx context.Context, id string) (*Job, error) {
+	var job Job
+	if err := c.do(ctx, http.MethodPost, "/jobs/"+url.PathEscape(id)+"/retry", nil, nil, false, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Purge deletes a finished job with its events and payload. Unfinished jobs
+// yield an error wrapping ErrConflict. It needs a jobs:admin key.
+func (c *Client) Purge(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/jobs/"+url.PathEscape(id), nil, nil, true, nil)
+}
+
+// Wait polls the job every interval until it reaches a terminal status or
+// ctx is done, and returns its last known state.
+func (c *Client) Wait(ctx context.Context, id string, interval time.Duration) (*Job, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		job, err := c.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Status.Terminal() {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Error is a non-2xx API response. It unwraps to the matching package
+// error (ErrNotFound, ErrInvalidInput, ...) when there is one.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    []FieldError
+	RequestID  string
+}
+
+// FieldError points at one invalid field of a rejected request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("api: %d %s", e.StatusCode, e.Code)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	switch {
+	case e.Code == "quota_exceeded":
+		return ErrQuotaExceeded
+	case e.Code == "dependency_failed":
+		return ErrDependencyFailed
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+// do sends one API call, retrying transient failures when retryable is set,
+// and decodes a 2xx JSON body into out.
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body any, retryable bool, out any) error {
+	var raw []byte
+	if body != nil {
+		var err error
+		if raw, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := c.newRequest(ctx, method, path, header, raw)
+		if err != nil {
+			return err
+		}
+
+		resp, err := c.httpClient.Do(req)
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || !retryable || attempt >= c.maxRetries {
+				return err
+			}
+		case resp.StatusCode < 300:
+			defer resp.Body.Close()
+			if out == nil {
+				return nil
+			}
+			return json.NewDecoder(resp.Body).Decode(out)
+		default:
+			apiErr := readError(resp)
+			// A full quota will not drain in a few seconds; only rate limits and
+			// unavailable upstreams are worth waiting for.
+			if !retryable || attempt >= c.maxRetries || !transient(resp.StatusCode) || apiErr.Code == "quota_exceeded" {
+				return apiErr
+			}
+			wait = retryAfter(resp)
+		}
+
+		if wait == 0 {
+			wait = c.backoff(attempt)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, header http.Header, raw []byte) (*http.Request, error) {
+	var reader io.Reader
+	if raw != nil {
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if raw != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return req, nil
+}
+
+func readError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+	apiErr := &Error{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Code      string       `json:"code"`
+		Message   string       `json:"message"`
+		Details   []FieldError `json:"details"`
+		RequestID string       `json:"request_id"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if json.Unmarshal(data, &body) == nil {
+		apiErr.Code = body.Code
+		apiErr.Message = body.Message
+		apiErr.Details = body.Details
+		apiErr.RequestID = body.RequestID
+	}
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = resp.Header.Get("X-Request-ID")
+	}
+	if apiErr.Code == "" {
+		apiErr.Code = strings.ToLower(strings.ReplaceAll(http.StatusText(resp.StatusCode), " ", "_"))
+	}
+	return apiErr
+}
+
+func transient(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// backoff doubles from minBackoff per attempt, capped at maxBackoff, with
+// up to 50% jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.minBackoff << attempt
+	if d <= 0 || d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,162 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"task-scheduler/internal/domain"
+)
+
+type (
+	JobEvent            = domain.JobEvent
+	QueueStats          = domain.QueueStats
+	PayloadVersionCount = domain.PayloadVersionCount
+	RetentionRun        = domain.RetentionRun
+)
+
+// Queues returns job counts by status and the pause state of each queue.
+func (c *Client) Queues(ctx context.Context) ([]QueueStats, error) {
+	var resp struct {
+		Queues []QueueStats `json:"queues"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/queues", nil, nil, true, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Queues, nil
+}
+
+// PauseQueue stops workers from claiming jobs in queue. Pausing is idempotent.
+func (c *Client) PauseQueue(ctx context.Context, queue string) error {
+	return c.do(ctx, http.MethodPost, "/queues/"+url.PathEscape(queue)+"/pause", nil, nil, true, nil)
+}
+
+// ResumeQueue undoes PauseQueue.
+func (c *Client) ResumeQueue(ctx context.Context, queue string) error {
+	return c.do(ctx, http.MethodPost, "/queues/"+url.PathEscape(queue)+"/resume", nil, nil, true, nil)
+}
+
+// PayloadVersions reports queued jobs per type and payload version. It needs
+// a jobs:admin key.
+func (c *Client) PayloadVersions(ctx context.Context) ([]PayloadVersionCount, error) {
+	var resp struct {
+		Versions []PayloadVersionCount `json:"versions"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/admin/payload-versions", nil, nil, true, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Versions, nil
+}
+
+// LastRetentionRun reports the latest retention sweep, or nil if none has
+// run. It needs a tenants:admin key.
+func (c *Client) LastRetentionRun(ctx context.Context) (*RetentionRun, error) {
+	var resp struct {
+		LastRun *RetentionRun `json:"last_run"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/admin/retention", nil, nil, true, &resp); err != nil {
+		return nil, err
+	}
+	return resp.LastRun, nil
+}
+
+// RetryJobsRequest selects jobs for RetryJobs. Status defaults to FAILED.
+type RetryJobsRequest struct {
+	Status JobStatus `json:"status,omitempty"`
+	Type   string    `json:"type,omitempty"`
+	Queue  string    `json:"queue,omitempty"`
+	Limit  int       `json:"limit,omitempty"`
+}
+
+// RetryJobsResult lists the requeued jobs. QuotaExceeded means the tenant's
+// queued quota stopped the run early.
+type RetryJobsResult struct {
+	Retried       []string `json:"retried"`
+	Skipped       int      `json:"skipped"`
+	QuotaExceeded bool     `json:"quota_exceeded"`
+}
+
+// RetryJobs requeues matching dead (FAILED) or CANCELLED jobs, newest first.
+func (c *Client) RetryJobs(ctx context.Context, req RetryJobsRequest) (*RetryJobsResult, error) {
+	var res RetryJobsResult
+	if err := c.do(ctx, http.MethodPost, "/jobs:retry", nil, req, false, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// EventOptions filters Events; zero values match everything. AfterID
+// resumes after a known event; by default only new events are streamed.
+type EventOptions struct {
+	JobID   string
+	Type    string
+	Queue   string
+	AfterID int64
+}
+
+// Events streams job events to fn until ctx is done, fn returns an error,
+// or the server closes the stream. The stream is not reconnected; resume by
+// passing the last event ID seen as AfterID.
+func (c *Client) Events(ctx context.Context, opts EventOptions, fn func(JobEvent) error) error {
+	q := url.Values{}
+	if opts.JobID != "" {
+		q.Set("job_id", opts.JobID)
+	}
+	if opts.Type != "" {
+		q.Set("type", opts.Type)
+	}
+	if opts.Queue != "" {
+		q.Set("queue", opts.Queue)
+	}
+	if opts.AfterID > 0 {
+		q.Set("last_event_id", strconv.FormatInt(opts.AfterID, 10))
+	}
+	path := "/events"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	header := http.Header{}
+	header.Set("Accept", "text/event-stream")
+	req, err := c.newRequest(ctx, http.MethodGet, path, header, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return readError(resp)
+	}
+	defer resp.Body.Close()
+
+	// Only "data:" lines matter; ids, retry hints and comments are skipped.
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	var data strings.Builder
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		case line == "" && data.Len() > 0:
+			var e JobEvent
+			if err := json.Unmarshal([]byte(data.String()), &e); err != nil {
+				return err
+			}
+			data.Reset()
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return sc.Err()
+}
@@ -0,0 +1,127 @@
+// Package enqueue lets services that share the scheduler's MySQL database
+// create jobs inside their own transactions, outbox-style:
+//
+//	tx, _ := db.BeginTx(ctx, nil)
+//	_, _ = tx.ExecContext(ctx, `INSERT INTO orders ...`)
+//	_, err := enqueue.Enqueue(ctx, tx, enqueue.Spec{
+//		TenantID: "acme",
+//		Type:     "send_receipt",
+//		Payload:  json.RawMessage(`{"order_id": 42}`),
+//	})
+//	...
+//	tx.Commit()
+//
+// The job is written with the same logic the API uses (quotas, idempotency
+// keys, unique keys, dependencies, creation event) and becomes claimable only
+// once the transaction commits. If it rolls back, the job is gone too.
+package enqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"task-scheduler/internal/domain"
+	"task-scheduler/internal/keyring"
+	mysqlrepo "task-scheduler/internal/repo/mysql"
+)
+
+type (
+	// Spec describes the job to create. ID is generated when empty.
+	Spec = domain.JobSpec
+	Job  = domain.Job
+
+	UniqueSpec = domain.UniqueSpec
+	Outcome    = domain.CreateOutcome
+)
+
+const (
+	OutcomeCreated      = domain.OutcomeCreated
+	OutcomeReplayed     = domain.OutcomeReplayed
+	OutcomeDeduplicated = domain.OutcomeDeduplicated
+	OutcomeReplaced     = domain.OutcomeReplaced
+)
+
+// Errors returned by Enqueue, for use with errors.Is.
+var (
+	ErrInvalidInput     = domain.ErrInvalidInput
+	ErrConflict         = domain.ErrConflict
+	ErrQuotaExceeded    = domain.ErrQuotaExceeded
+	ErrDependencyFailed = domain.ErrDependencyFailed
+)
+
+// Enqueue creates spec in tx and returns the job as tx sees it. With an
+// idempotency key or unique key the job may be an existing one (see
+// EnqueueOutcome). On error, tx may hold partial writes and should be rolled back.
+//
+// The payload is stored in plaintext and uncompressed; use an Enqueuer to
+// encrypt or compress it.
+func Enqueue(ctx context.Context, tx *sql.Tx, spec Spec) (*Job, error) {
+	return defaultEnqueuer.Enqueue(ctx, tx, spec)
+}
+
+// EnqueueOutcome is Enqueue that also reports whether the job was created,
+// replayed, deduplicated or replaced.
+func EnqueueOutcome(ctx context.Context, tx *sql.Tx, spec Spec) (*Job, Outcome, error) {
+	return defaultEnqueuer.EnqueueOutcome(ctx, tx, spec)
+}
+
+var defaultEnqueuer = &Enqueuer{}
+
+// Options sets how an Enqueuer stores payloads. The zero value stores them
+// in plaintext.
+type Options struct {
+	// KeyringFile is the keyring the scheduler uses (PAYLOAD_KEYRING_FILE).
+	// If set, payloads are encrypted with it, so they are not stored in
+	// plaintext.
+	KeyringFile string
+
+	// CompressMinBytes, if set, stores payloads of at least this size
+	// gzip-compressed, like the scheduler does with COMPRESS_MIN_BYTES.
+	CompressMinBytes int
+}
+
+// Enqueuer creates jobs like Enqueue, with its own payload settings.
+type Enqueuer struct {
+	payloads mysqlrepo.PayloadOptions
+}
+
+// New returns an Enqueuer for opts. Create it once at startup.
+func New(opts Options) (*Enqueuer, error) {
+	payloads := mysqlrepo.PayloadOptions{CompressMin: opts.CompressMinBytes}
+	if opts.KeyringFile != "" {
+		kr, err := keyring.Load(opts.KeyringFile)
+		if err != nil {
+			return nil, err
+		}
+		payloads.Keyring = kr
+	}
+	return &Enqueuer{payloads: payloads}, nil
+}
+
+// Enqueue is the package-level Enqueue with e's payload settings.
+func (e *Enqueuer) Enqueue(ctx context.Context, tx *sql.Tx, spec Spec) (*Job, error) {
+	job, _, err := e.EnqueueOutcome(ctx, tx, spec)
+	return job, err
+}
+
+// EnqueueOutcome is the package-level EnqueueOutcome with e's payload
+// settings.
+func (e *Enqueuer) EnqueueOutcome(ctx context.Context, tx *sql.Tx, spec Spec) (*Job, Outcome, error) {
+	if spec.ID == "" {
+		spec.ID = NewID()
+	}
+	return mysqlrepo.EnqueueTx(ctx, tx, e.payloads, spec)
+}
+
+// NewID returns a time-ordered job ID with a random suffix, so IDs from
+// several processes do not collide.
+func NewID() string {
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	ts := strings.ReplaceAll(time.Now().UTC().Format("20060102150405.000000000"), ".", "")
+	return ts + "-" + hex.EncodeToString(b[:])
+}
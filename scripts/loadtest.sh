@@ -3,12 +3,14 @@ set -euo pipefail
 
 BASE_URL="${BASE_URL:-http://localhost:8086}"
 N="${N:-50}"
+API_KEY="${API_KEY:?API_KEY is required (jobs:create scope)}"
 
 echo "Load test: creating ${N} jobs against ${BASE_URL}"
 
 for i in $(seq 1 "$N"); do
   curl -sS -X POST "${BASE_URL}/jobs" \
     -H "Content-Type: application/json" \
+    -H "Authorization: Bearer ${API_KEY}" \
     -H "Idempotency-Key: load-${i}" \
     -d "{\"type\":\"demo\",\"payload\":{\"i\":${i}},\"max_attempts\":3}" >/dev/null &
 done